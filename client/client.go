@@ -0,0 +1,235 @@
+// Package client is a service.Service that calls a storage server over HTTP,
+// through go-kit client endpoints. It re-exports the types and errors of its
+// API, which other modules cannot import from storage/internal.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/service"
+
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// Config tells a Client where the server is and how to call it.
+type Config struct {
+	// HTTPClient sends the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// APIKey is sent in the X-API-Key header of every request, if not empty.
+	APIKey string
+	// BaseURL is the URL the routes hang from, including the uri_prefix of
+	// the server, e.g. "http://localhost:7070/api/v1".
+	BaseURL string
+	// Timeout bounds every attempt of a call, 0 for no timeout other than
+	// the deadline of the context.
+	Timeout time.Duration
+	// Retries is how many times an idempotent call is retried after a
+	// network error or a 502, 503 or 504 response.
+	Retries int
+	// Backoff is the wait before the first retry, doubled before each next
+	// one. It defaults to DefaultBackoff.
+	Backoff time.Duration
+}
+
+// Client implements service.Service over HTTP. Failed calls return an *Error
+// that wraps the matching error of this package, if any, so that for
+// instance errors.Is(err, ErrUserNotFound) holds for a missing user.
+type Client struct {
+	endpoints endpoint.Endpoints
+}
+
+// DefaultBackoff is the Backoff of a Config that sets none.
+const DefaultBackoff = 100 * time.Millisecond
+
+var ErrInvalidBaseURL = errors.New("invalid base URL")
+
+var _ service.Service = (*Client)(nil)
+
+// New returns a Client for the server at cfg.BaseURL.
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBaseURL, cfg.BaseURL)
+	}
+
+	base.Path = strings.TrimSuffix(base.Path, "/")
+
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = DefaultBackoff
+	}
+
+	options := []httptransport.ClientOption{httptransport.ClientBefore(requestIDToHeader)}
+	if cfg.APIKey != "" {
+		options = append(options, httptransport.ClientBefore(apiKeyToHeader(cfg.APIKey)))
+	}
+
+	if cfg.HTTPClient != nil {
+		options = append(options, httptransport.SetClient(cfg.HTTPClient))
+	}
+
+	// newEndpoint returns the endpoint of a route. Only the idempotent ones,
+	// GET and DELETE, are retried.
+	newEndpoint := func(
+		method string,
+		enc httptransport.EncodeRequestFunc,
+		dec httptransport.DecodeResponseFunc,
+	) kitendpoint.Endpoint {
+		e := httptransport.NewClient(method, base, enc, dec, options...).Endpoint()
+		e = endpoint.TimeoutMiddleware(cfg.Timeout)(e)
+
+		if method == http.MethodGet || method == http.MethodDelete {
+			e = retryMiddleware(cfg.Retries, cfg.Backoff)(e)
+		}
+
+		return e
+	}
+
+	return &Client{endpoints: endpoint.Endpoints{
+		GetAllUsers: newEndpoint(
+			http.MethodGet, encodeListUsersRequest, decodeResponse[entity.UsersErrorResponse],
+		),
+		GetUserByID: newEndpoint(
+			http.MethodGet, encodeUserPath, decodeResponse[entity.UserErrorResponse],
+		),
+		GetUserByUsernameAndPassword: newEndpoint(
+			http.MethodPost, encodeJSON("/auth/verify"), decodeResponse[entity.UserErrorResponse],
+		),
+		GetIDByUsername: newEndpoint(
+			http.MethodGet, encodeUsernameQuery, decodeResponse[entity.IDErrorResponse],
+		),
+		InsertUser: newEndpoint(
+			http.MethodPost, encodeJSON("/users"), decodeResponse[entity.ErrorResponse],
+		),
+		UpdateUser: newEndpoint(
+			http.MethodPatch, encodeUpdateUserRequest, decodeResponse[entity.UserErrorResponse],
+		),
+		DeleteUser: newEndpoint(
+			http.MethodDelete, encodeUserPath, decodeResponse[entity.RowsErrorResponse],
+		),
+		CountLegacyPasswords: newEndpoint(
+			http.MethodGet, encodePath("/stats/legacy_passwords"), decodeResponse[entity.CountErrorResponse],
+		),
+		UnlockUser: newEndpoint(
+			http.MethodPost, encodeUnlockUserPath, decodeResponse[entity.ErrorResponse],
+		),
+	}}, nil
+}
+
+// GetAllUsers ...
+func (c *Client) GetAllUsers(
+	ctx context.Context,
+	opts ListUsersRequest,
+) (users []User, nextCursor string, err error) {
+	response, err := c.endpoints.GetAllUsers(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, _ := response.(entity.UsersErrorResponse)
+
+	return resp.Users, resp.NextCursor, nil
+}
+
+// GetUserByID ...
+func (c *Client) GetUserByID(ctx context.Context, id int) (user User, err error) {
+	response, err := c.endpoints.GetUserByID(ctx, entity.IDRequest{ID: id})
+	if err != nil {
+		return User{}, err
+	}
+
+	resp, _ := response.(entity.UserErrorResponse)
+
+	return resp.User, nil
+}
+
+// GetUserByUsernameAndPassword ...
+func (c *Client) GetUserByUsernameAndPassword(
+	ctx context.Context,
+	username, plainPassword string,
+) (user User, err error) {
+	response, err := c.endpoints.GetUserByUsernameAndPassword(
+		ctx,
+		entity.UsernamePasswordRequest{Username: username, Password: plainPassword},
+	)
+	if err != nil {
+		return User{}, err
+	}
+
+	resp, _ := response.(entity.UserErrorResponse)
+
+	return resp.User, nil
+}
+
+// GetIDByUsername ...
+func (c *Client) GetIDByUsername(ctx context.Context, username string) (id int, err error) {
+	response, err := c.endpoints.GetIDByUsername(ctx, entity.UsernameRequest{Username: username})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, _ := response.(entity.IDErrorResponse)
+
+	return resp.ID, nil
+}
+
+// InsertUser ...
+func (c *Client) InsertUser(ctx context.Context, username, plainPassword, email string) (err error) {
+	_, err = c.endpoints.InsertUser(
+		ctx,
+		entity.UsernamePasswordEmailRequest{Username: username, Password: plainPassword, Email: email},
+	)
+
+	return err
+}
+
+// UpdateUser ...
+func (c *Client) UpdateUser(ctx context.Context, id int, patch UserPatch) (user User, err error) {
+	response, err := c.endpoints.UpdateUser(ctx, entity.UpdateUserRequest{ID: id, Patch: patch})
+	if err != nil {
+		return User{}, err
+	}
+
+	resp, _ := response.(entity.UserErrorResponse)
+
+	return resp.User, nil
+}
+
+// DeleteUser ...
+func (c *Client) DeleteUser(ctx context.Context, id int) (rowsAffected int, err error) {
+	response, err := c.endpoints.DeleteUser(ctx, entity.IDRequest{ID: id})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, _ := response.(entity.RowsErrorResponse)
+
+	return resp.RowsAffected, nil
+}
+
+// CountLegacyPasswords ...
+func (c *Client) CountLegacyPasswords(ctx context.Context) (count int, err error) {
+	response, err := c.endpoints.CountLegacyPasswords(ctx, entity.EmptyRequest{})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, _ := response.(entity.CountErrorResponse)
+
+	return resp.Count, nil
+}
+
+// UnlockUser ...
+func (c *Client) UnlockUser(ctx context.Context, id int) (err error) {
+	_, err = c.endpoints.UnlockUser(ctx, entity.IDRequest{ID: id})
+
+	return err
+}
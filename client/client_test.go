@@ -0,0 +1,493 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"storage/client"
+	"storage/internal/auth"
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/password"
+	"storage/internal/repository"
+	"storage/internal/requestid"
+	"storage/internal/service"
+	"storage/internal/transport"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	otherUsernameTest = "other"
+	otherEmailTest    = "other@other.com"
+	uriPrefixTest     = "/api/v1"
+
+	lockoutThresholdTest = 2
+)
+
+// newServer serves the real handlers under uriPrefixTest, over a memory
+// repository holding the mock user, with ID mock.IDTest, and the other user,
+// locked out for an hour after lockoutThresholdTest failed logins.
+// Requests go through wrap, if not nil, before reaching the handlers.
+func newServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+
+	passwordHashed, err := hasher.Hash(mock.PasswordTest)
+	assert.Nil(t, err)
+
+	repo := repository.NewMemory()
+
+	for _, user := range []entity.User{
+		{Username: mock.UsernameTest, Password: passwordHashed, Email: mock.EmailTest},
+		{Username: otherUsernameTest, Password: passwordHashed, Email: otherEmailTest},
+	} {
+		err = repo.InsertUser(context.TODO(), user)
+		assert.Nil(t, err)
+	}
+
+	router := mux.NewRouter()
+	transport.RegisterRoutes(
+		router.PathPrefix(uriPrefixTest).Subrouter(),
+		endpoint.MakeEndpoints(service.GetService(repo, hasher).WithLockout(repo, service.LockoutPolicy{
+			Threshold: lockoutThresholdTest,
+			Duration:  time.Hour,
+		})),
+	)
+
+	var handler http.Handler = router
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newClient(t *testing.T, cfg client.Config) *client.Client {
+	t.Helper()
+
+	c, err := client.New(cfg)
+	assert.Nil(t, err)
+
+	return c
+}
+
+// failFirst answers the first n requests with status, and lets the next
+// ones through, counting every request in count.
+func failFirst(n int32, status int, count *int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(count, 1) <= n {
+				w.WriteHeader(status)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// getMockUser is an idempotent call.
+func getMockUser(c *client.Client) error {
+	_, err := c.GetUserByID(context.TODO(), mock.IDTest)
+
+	return err
+}
+
+// insertNewUser is a call that is not idempotent.
+func insertNewUser(c *client.Client) error {
+	return c.InsertUser(context.TODO(), "new", mock.PasswordTest, "new@new.com")
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		inBaseURL string
+		outErr    error
+	}{
+		{name: mock.NameNoError, inBaseURL: "http://localhost:7070/api/v1/"},
+		{name: "NoScheme", inBaseURL: "localhost:7070", outErr: client.ErrInvalidBaseURL},
+		{name: "Empty", inBaseURL: "", outErr: client.ErrInvalidBaseURL},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := client.New(client.Config{BaseURL: tt.inBaseURL})
+
+			assert.ErrorIs(t, err, tt.outErr)
+		})
+	}
+}
+
+func TestGetUserByID(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		outErr      error
+		name        string
+		outUsername string
+		inID        int
+		outStatus   int
+	}{
+		{
+			name:        mock.NameNoError,
+			inID:        mock.IDTest,
+			outUsername: mock.UsernameTest,
+		},
+		{
+			name:      mock.NameErrorNoRows,
+			inID:      mock.IDTest + 9,
+			outErr:    client.ErrUserNotFound,
+			outStatus: http.StatusNotFound,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+			user, err := c.GetUserByID(requestid.NewContext(context.TODO(), "abc-123"), tt.inID)
+
+			assert.ErrorIs(t, err, tt.outErr)
+			assert.Equal(t, tt.outUsername, user.Username)
+
+			var clientErr *client.Error
+
+			if tt.outErr != nil && assert.ErrorAs(t, err, &clientErr) {
+				assert.Equal(t, tt.outStatus, clientErr.StatusCode)
+				assert.Equal(t, transport.CodeUserNotFound, clientErr.Code)
+				assert.Equal(t, "abc-123", clientErr.RequestID)
+				assert.Equal(t, client.ErrUserNotFound.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestGetAllUsers(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+	opts := client.ListUsersRequest{SortBy: "username", Descending: true, Limit: 1}
+
+	users, nextCursor, err := c.GetAllUsers(context.TODO(), opts)
+	assert.Nil(t, err)
+
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, mock.UsernameTest, users[0].Username)
+	}
+
+	opts.Cursor = nextCursor
+
+	users, nextCursor, err = c.GetAllUsers(context.TODO(), opts)
+	assert.Nil(t, err)
+	assert.Empty(t, nextCursor)
+
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, otherUsernameTest, users[0].Username)
+	}
+
+	_, _, err = c.GetAllUsers(context.TODO(), client.ListUsersRequest{SortBy: "password"})
+	assert.ErrorIs(t, err, client.ErrInvalidListOptions)
+}
+
+func TestGetUserByUsernameAndPassword(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		outErr     error
+		name       string
+		inPassword string
+	}{
+		{name: mock.NameNoError, inPassword: mock.PasswordTest},
+		{name: "InvalidCredentials", inPassword: "wrong", outErr: client.ErrInvalidCredentials},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+			user, err := c.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, tt.inPassword)
+
+			assert.ErrorIs(t, err, tt.outErr)
+
+			if tt.outErr == nil {
+				assert.Equal(t, mock.IDTest, user.ID)
+			}
+		})
+	}
+}
+
+func TestGetIDByUsername(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+	id, err := c.GetIDByUsername(context.TODO(), otherUsernameTest)
+	assert.Nil(t, err)
+	assert.Equal(t, mock.IDTest+1, id)
+
+	_, err = c.GetIDByUsername(context.TODO(), "nobody")
+	assert.ErrorIs(t, err, client.ErrUserNotFound)
+}
+
+func TestInsertUser(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		outErr     error
+		name       string
+		inUsername string
+		inEmail    string
+	}{
+		{name: mock.NameNoError, inUsername: "new", inEmail: "new@new.com"},
+		{name: "UsernameTaken", inUsername: mock.UsernameTest, inEmail: "new@new.com", outErr: client.ErrUsernameTaken},
+		{name: "EmailTaken", inUsername: "new", inEmail: mock.EmailTest, outErr: client.ErrEmailTaken},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+			err := c.InsertUser(context.TODO(), tt.inUsername, mock.PasswordTest, tt.inEmail)
+
+			assert.ErrorIs(t, err, tt.outErr)
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		outErr   error
+		name     string
+		inPatch  client.UserPatch
+		outEmail string
+	}{
+		{
+			name:     mock.NameNoError,
+			inPatch:  client.UserPatch{Email: &[]string{"new@new.com"}[0]},
+			outEmail: "new@new.com",
+		},
+		{
+			name:    "EmailTaken",
+			inPatch: client.UserPatch{Email: &[]string{otherEmailTest}[0]},
+			outErr:  client.ErrEmailTaken,
+		},
+		{
+			name:    "BadRequest",
+			inPatch: client.UserPatch{Username: &[]string{""}[0]},
+			outErr:  client.ErrBadRequest,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+			user, err := c.UpdateUser(context.TODO(), mock.IDTest, tt.inPatch)
+
+			assert.ErrorIs(t, err, tt.outErr)
+			assert.Equal(t, tt.outEmail, user.Email)
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+	rowsAffected, err := c.DeleteUser(context.TODO(), mock.IDTest)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, rowsAffected)
+
+	_, err = c.GetUserByID(context.TODO(), mock.IDTest)
+	assert.ErrorIs(t, err, client.ErrUserNotFound)
+}
+
+func TestCountLegacyPasswords(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+	count, err := c.CountLegacyPasswords(context.TODO())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestUnlockUser(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(t, client.Config{BaseURL: newServer(t, nil).URL + uriPrefixTest})
+
+	for i := 0; i < lockoutThresholdTest; i++ {
+		_, err := c.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+		assert.ErrorIs(t, err, client.ErrInvalidCredentials)
+	}
+
+	_, err := c.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, mock.PasswordTest)
+	assert.ErrorIs(t, err, client.ErrUserLocked)
+
+	var clientErr *client.Error
+
+	if assert.ErrorAs(t, err, &clientErr) {
+		assert.Equal(t, http.StatusLocked, clientErr.StatusCode)
+	}
+
+	err = c.UnlockUser(context.TODO(), mock.IDTest)
+	assert.Nil(t, err)
+
+	_, err = c.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, mock.PasswordTest)
+	assert.Nil(t, err)
+
+	err = c.UnlockUser(context.TODO(), mock.IDTest+9)
+	assert.ErrorIs(t, err, client.ErrUserNotFound)
+}
+
+func TestRetries(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		call        func(c *client.Client) error
+		name        string
+		inStatus    int
+		inRetries   int
+		outRequests int32
+		outErr      bool
+	}{
+		{
+			name:        mock.NameNoError,
+			call:        getMockUser,
+			inStatus:    http.StatusServiceUnavailable,
+			inRetries:   2,
+			outRequests: 3,
+		},
+		{
+			name:        "TooManyFailures",
+			call:        getMockUser,
+			inStatus:    http.StatusServiceUnavailable,
+			inRetries:   1,
+			outRequests: 2,
+			outErr:      true,
+		},
+		{
+			name:        "NotRetryableStatus",
+			call:        getMockUser,
+			inStatus:    http.StatusInternalServerError,
+			inRetries:   2,
+			outRequests: 1,
+			outErr:      true,
+		},
+		{
+			name:        "NotIdempotent",
+			call:        insertNewUser,
+			inStatus:    http.StatusServiceUnavailable,
+			inRetries:   2,
+			outRequests: 1,
+			outErr:      true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var requests int32
+
+			server := newServer(t, failFirst(2, tt.inStatus, &requests))
+
+			c := newClient(t, client.Config{
+				BaseURL: server.URL + uriPrefixTest,
+				Retries: tt.inRetries,
+				Backoff: time.Millisecond,
+			})
+
+			err := tt.call(c)
+
+			assert.Equal(t, tt.outErr, err != nil)
+			assert.Equal(t, tt.outRequests, atomic.LoadInt32(&requests))
+
+			var clientErr *client.Error
+
+			if tt.outErr && assert.ErrorAs(t, err, &clientErr) {
+				assert.Equal(t, tt.inStatus, clientErr.StatusCode)
+				assert.Nil(t, errors.Unwrap(err))
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	slow := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	c := newClient(t, client.Config{
+		BaseURL: newServer(t, slow).URL + uriPrefixTest,
+		Timeout: 10 * time.Millisecond,
+	})
+
+	_, err := c.GetUserByID(context.TODO(), mock.IDTest)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAPIKey(t *testing.T) {
+	t.Parallel()
+
+	requireKey := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(auth.Header) != "sk_test" {
+				transport.EncodeError(r.Context(), client.ErrUnauthenticated, w)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	server := newServer(t, requireKey)
+
+	err := getMockUser(newClient(t, client.Config{BaseURL: server.URL + uriPrefixTest}))
+	assert.ErrorIs(t, err, client.ErrUnauthenticated)
+
+	var clientErr *client.Error
+
+	if assert.ErrorAs(t, err, &clientErr) {
+		assert.Equal(t, http.StatusUnauthorized, clientErr.StatusCode)
+	}
+
+	err = getMockUser(newClient(t, client.Config{BaseURL: server.URL + uriPrefixTest, APIKey: "sk_test"}))
+	assert.Nil(t, err)
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"storage/internal/entity"
+	"storage/internal/requestid"
+	"storage/internal/transport"
+)
+
+// Error is a failed response of the server.
+type Error struct {
+	// Err is the error the server failed with, as one of the errors of this
+	// package, or nil when Code has no counterpart, e.g. internal_error.
+	Err error
+	// Message is the err member of the body.
+	Message string
+	// Code is the machine-readable code of the body, e.g. user_not_found.
+	Code string
+	// RequestID identifies the request in the logs of the server.
+	RequestID  string
+	StatusCode int
+}
+
+// ErrInvalidResponse is returned for a successful response whose body cannot
+// be decoded.
+var ErrInvalidResponse = errors.New("invalid response")
+
+// codeErrors maps the codes of the server back to its errors.
+var codeErrors = map[string]error{
+	transport.CodeBadRequest:         ErrBadRequest,
+	transport.CodeInvalidListOptions: ErrInvalidListOptions,
+	transport.CodeUserNotFound:       ErrUserNotFound,
+	transport.CodeUsernameTaken:      ErrUsernameTaken,
+	transport.CodeEmailTaken:         ErrEmailTaken,
+	transport.CodeInvalidCredentials: ErrInvalidCredentials,
+	transport.CodeUserLocked:         ErrUserLocked,
+	transport.CodeRateLimited:        ErrRateLimited,
+	transport.CodeUnauthenticated:    ErrUnauthenticated,
+	transport.CodeForbidden:          ErrForbidden,
+}
+
+// Error ...
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.StatusCode)
+	}
+
+	return e.Message
+}
+
+// Unwrap ...
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// decodeError decodes the entity.ErrorBody of r, falling back to the status
+// code when the body is not one.
+func decodeError(r *http.Response) *Error {
+	var body entity.ErrorBody
+
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	requestID := body.RequestID
+	if requestID == "" {
+		requestID = r.Header.Get(requestid.Header)
+	}
+
+	return &Error{
+		Err:        codeErrors[body.Code],
+		Message:    body.Err,
+		Code:       body.Code,
+		RequestID:  requestID,
+		StatusCode: r.StatusCode,
+	}
+}
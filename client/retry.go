@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// retryMiddleware calls the endpoint again, up to retries times, while it
+// fails with a network error or a 502, 503 or 504 response, waiting backoff
+// before the first retry and twice as long before each next one.
+func retryMiddleware(retries int, backoff time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request any) (response any, err error) {
+			for attempt := 0; ; attempt++ {
+				response, err = next(ctx, request)
+				if err == nil || attempt >= retries || !retryable(ctx, err) {
+					return response, err
+				}
+
+				timer := time.NewTimer(backoff << attempt)
+
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+
+					return nil, err
+				case <-timer.C:
+				}
+			}
+		}
+	}
+}
+
+// retryable reports whether a call that failed with err, and whose context is
+// ctx, may succeed if retried.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrInvalidResponse) {
+		return false
+	}
+
+	var clientErr *Error
+
+	if !errors.As(err, &clientErr) {
+		return true
+	}
+
+	switch clientErr.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
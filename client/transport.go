@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/requestid"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// encodePath returns the EncodeRequestFunc of the route at path, which sends
+// no body.
+func encodePath(path string) httptransport.EncodeRequestFunc {
+	return func(_ context.Context, r *http.Request, _ any) error {
+		r.URL.Path += path
+
+		return nil
+	}
+}
+
+// encodeJSON returns the EncodeRequestFunc of the route at path, which sends
+// the request as a JSON body.
+func encodeJSON(path string) httptransport.EncodeRequestFunc {
+	return func(ctx context.Context, r *http.Request, request any) error {
+		r.URL.Path += path
+
+		return httptransport.EncodeJSONRequest(ctx, r, request)
+	}
+}
+
+// encodeUserPath encodes an entity.IDRequest in the /users/{id} path.
+func encodeUserPath(_ context.Context, r *http.Request, request any) error {
+	req, _ := request.(entity.IDRequest)
+
+	r.URL.Path += "/users/" + strconv.Itoa(req.ID)
+
+	return nil
+}
+
+// encodeUnlockUserPath encodes an entity.IDRequest in the
+// /users/{id}/unlock path.
+func encodeUnlockUserPath(ctx context.Context, r *http.Request, request any) error {
+	if err := encodeUserPath(ctx, r, request); err != nil {
+		return err
+	}
+
+	r.URL.Path += "/unlock"
+
+	return nil
+}
+
+// encodeUsernameQuery encodes an entity.UsernameRequest in the username query
+// parameter of /users.
+func encodeUsernameQuery(_ context.Context, r *http.Request, request any) error {
+	req, _ := request.(entity.UsernameRequest)
+
+	r.URL.Path += "/users"
+	r.URL.RawQuery = url.Values{"username": {req.Username}}.Encode()
+
+	return nil
+}
+
+// encodeListUsersRequest encodes an entity.ListUsersRequest in the query
+// parameters of /users, leaving out those with their default value.
+func encodeListUsersRequest(_ context.Context, r *http.Request, request any) error {
+	req, _ := request.(entity.ListUsersRequest)
+
+	query := url.Values{}
+
+	set := func(name, value string) {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	if req.Limit != 0 {
+		set("limit", strconv.Itoa(req.Limit))
+	}
+
+	if req.Descending {
+		set("order", "desc")
+	}
+
+	set("cursor", req.Cursor)
+	set("sort", req.SortBy)
+	set("usernamePrefix", req.UsernamePrefix)
+	set("emailDomain", req.EmailDomain)
+
+	r.URL.Path += "/users"
+	r.URL.RawQuery = query.Encode()
+
+	return nil
+}
+
+// encodeUpdateUserRequest encodes an entity.UpdateUserRequest as a JSON Merge
+// Patch of /users/{id} holding the non-nil fields of the patch.
+func encodeUpdateUserRequest(ctx context.Context, r *http.Request, request any) error {
+	req, _ := request.(entity.UpdateUserRequest)
+
+	members := make(map[string]string)
+
+	for name, value := range map[string]*string{
+		"username": req.Patch.Username,
+		"email":    req.Patch.Email,
+		"password": req.Patch.Password,
+	} {
+		if value != nil {
+			members[name] = *value
+		}
+	}
+
+	r.URL.Path += "/users/" + strconv.Itoa(req.ID)
+
+	if err := httptransport.EncodeJSONRequest(ctx, r, members); err != nil {
+		return err
+	}
+
+	r.Header.Set("Content-Type", "application/merge-patch+json")
+
+	return nil
+}
+
+// decodeResponse decodes a successful response into a T, and a failed one
+// into an *Error.
+func decodeResponse[T any](_ context.Context, r *http.Response) (any, error) {
+	if r.StatusCode >= http.StatusBadRequest {
+		return nil, decodeError(r)
+	}
+
+	var response T
+
+	if err := json.NewDecoder(r.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+
+	return response, nil
+}
+
+// requestIDToHeader sends the request ID of ctx, if any, in the X-Request-ID
+// header, so that the logs of the server can be correlated with the caller's.
+func requestIDToHeader(ctx context.Context, r *http.Request) context.Context {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.Header.Set(requestid.Header, id)
+	}
+
+	return ctx
+}
+
+// apiKeyToHeader sends apiKey in the X-API-Key header.
+func apiKeyToHeader(apiKey string) httptransport.RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		r.Header.Set(auth.Header, apiKey)
+
+		return ctx
+	}
+}
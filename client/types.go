@@ -0,0 +1,36 @@
+package client
+
+import (
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/service"
+	"storage/internal/transport"
+
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+)
+
+// The types of the Client methods, which the modules outside this one cannot
+// import from storage/internal.
+type (
+	// User is a user as the server returns it.
+	User = entity.User
+	// UserPatch holds the changes UpdateUser applies. Nil fields are left
+	// untouched.
+	UserPatch = entity.UserPatch
+	// ListUsersRequest filters, sorts and pages GetAllUsers.
+	ListUsersRequest = entity.ListUsersRequest
+)
+
+// The errors an *Error wraps, to be matched with errors.Is.
+var (
+	ErrBadRequest         = transport.ErrBadRequest
+	ErrInvalidListOptions = service.ErrInvalidListOptions
+	ErrUserNotFound       = service.ErrUserNotFound
+	ErrUsernameTaken      = service.ErrUsernameTaken
+	ErrEmailTaken         = service.ErrEmailTaken
+	ErrInvalidCredentials = service.ErrInvalidCredentials
+	ErrUserLocked         = service.ErrUserLocked
+	ErrRateLimited        = kitratelimit.ErrLimited
+	ErrUnauthenticated    = auth.ErrUnauthenticated
+	ErrForbidden          = auth.ErrForbidden
+)
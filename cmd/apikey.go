@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"storage/cmd/config"
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/repository"
+)
+
+var ErrUnknownAPIKeyCommand = errors.New(
+	"unknown apikey command, use create <name> <scopes>, list or revoke <name>",
+)
+
+// runAPIKey runs "apikey create <name> <scopes>", "apikey list" or
+// "apikey revoke <name>" against the configured database. The secret of a
+// created key is printed once, as only its hash is stored.
+func runAPIKey(conn config.DBConfig, args []string) error {
+	if len(args) == 0 {
+		return ErrUnknownAPIKeyCommand
+	}
+
+	db, err := openDB(conn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgres(db)
+	if conn.Driver == "sqlite" {
+		repo = repository.NewSQLite(db)
+	}
+
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "create" && len(args) == 3:
+		var scopes []string
+
+		scopes, err = auth.ParseScopes(args[2])
+		if err != nil {
+			return err
+		}
+
+		var secret string
+
+		secret, err = auth.GenerateSecret()
+		if err != nil {
+			return err
+		}
+
+		err = repo.InsertAPIKey(ctx, entity.APIKey{Name: args[1], Hash: auth.Hash(secret), Scopes: scopes})
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(secret)
+	case args[0] == "list" && len(args) == 1:
+		var keys []entity.APIKey
+
+		keys, err = repo.ListAPIKeys(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			fmt.Printf("%s\t%s\t%s\n", key.Name, strings.Join(key.Scopes, ","), key.CreatedAt.Format("2006-01-02"))
+		}
+	case args[0] == "revoke" && len(args) == 2:
+		var rowsAffected int
+
+		rowsAffected, err = repo.DeleteAPIKey(ctx, args[1])
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return fmt.Errorf("%w: %q", auth.ErrKeyNotFound, args[1])
+		}
+
+		log.Printf("revoked %s", args[1])
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownAPIKeyCommand, strings.Join(args, " "))
+	}
+
+	return nil
+}
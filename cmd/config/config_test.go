@@ -0,0 +1,107 @@
+package config_test
+
+import (
+	"testing"
+
+	"storage/cmd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeURIPrefix(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		in     string
+		out    string
+		outErr string
+	}{
+		{
+			name: "Empty",
+			in:   "",
+			out:  "",
+		},
+		{
+			name: "Root",
+			in:   "/",
+			out:  "",
+		},
+		{
+			name: "LeadingSlash",
+			in:   "/api/v1",
+			out:  "/api/v1",
+		},
+		{
+			name: "NoLeadingSlash",
+			in:   "api/v1",
+			out:  "/api/v1",
+		},
+		{
+			name: "TrailingSlash",
+			in:   " /api/v1/ ",
+			out:  "/api/v1",
+		},
+		{
+			name:   "EmptySegment",
+			in:     "/api//v1",
+			outErr: config.ErrInvalidURIPrefix.Error(),
+		},
+		{
+			name:   "DotSegment",
+			in:     "/api/../v1",
+			outErr: config.ErrInvalidURIPrefix.Error(),
+		},
+		{
+			name:   "InvalidCharacter",
+			in:     "/api?v=1",
+			outErr: config.ErrInvalidURIPrefix.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			prefix, err := config.NormalizeURIPrefix(tt.in)
+			if tt.outErr == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.out, prefix)
+			} else {
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestListenAddr(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{
+			name: "WithoutColon",
+			in:   "7070",
+			out:  ":7070",
+		},
+		{
+			name: "WithColon",
+			in:   ":8080",
+			out:  ":8080",
+		},
+		{
+			name: "WithHost",
+			in:   "localhost:8080",
+			out:  "localhost:8080",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.out, config.ListenAddr(tt.in))
+		})
+	}
+}
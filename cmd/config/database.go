@@ -0,0 +1,239 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DBConfig holds how to reach the database. URL, when set, replaces every
+// other Postgres setting but the pool.
+type DBConfig struct {
+	Driver          string
+	Path            string
+	URL             string
+	Host            string
+	Port            string
+	User            string
+	Password        string
+	DBName          string
+	SSLMode         string
+	SSLRootCert     string
+	SSLCert         string
+	SSLKey          string
+	ApplicationName string
+	SearchPath      string
+	ConnectTimeout  time.Duration
+	Pool            PoolConfig
+}
+
+// PoolConfig holds the settings of the sql.DB connection pool.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+const redactedPassword = "xxxxx"
+
+// ErrInvalidDatabaseURL does not wrap the parse error, which quotes the URL
+// and with it the password.
+var ErrInvalidDatabaseURL = errors.New("invalid database_url")
+
+func dbConfig(cfg map[string]any) (db DBConfig, err error) {
+	db = DBConfig{
+		Driver:          cfg["database_driver"].(string),
+		Path:            cfg["database_path"].(string),
+		URL:             cfg["database_url"].(string),
+		Host:            cfg["database_host"].(string),
+		Port:            cfg["database_port"].(string),
+		User:            cfg["database_user"].(string),
+		Password:        cfg["database_pass"].(string),
+		DBName:          cfg["database_name"].(string),
+		SSLMode:         cfg["database_sslmode"].(string),
+		SSLRootCert:     cfg["database_sslrootcert"].(string),
+		SSLCert:         cfg["database_sslcert"].(string),
+		SSLKey:          cfg["database_sslkey"].(string),
+		ApplicationName: cfg["database_application_name"].(string),
+		SearchPath:      cfg["database_search_path"].(string),
+	}
+
+	if db.URL != "" {
+		if _, err = url.Parse(db.URL); err != nil {
+			return DBConfig{}, ErrInvalidDatabaseURL
+		}
+	}
+
+	err = parseDurations(cfg, map[string]*time.Duration{
+		"database_connect_timeout":    &db.ConnectTimeout,
+		"database_conn_max_lifetime":  &db.Pool.ConnMaxLifetime,
+		"database_conn_max_idle_time": &db.Pool.ConnMaxIdleTime,
+	})
+	if err != nil {
+		return DBConfig{}, err
+	}
+
+	err = parseInts(cfg, map[string]*int{
+		"database_max_open_conns": &db.Pool.MaxOpenConns,
+		"database_max_idle_conns": &db.Pool.MaxIdleConns,
+	})
+	if err != nil {
+		return DBConfig{}, err
+	}
+
+	return db, nil
+}
+
+// PostgresDSN returns the lib/pq connection string, which is URL when set.
+func (c DBConfig) PostgresDSN() string {
+	if c.URL != "" {
+		return c.URL
+	}
+
+	return c.keywordValueDSN(c.Password)
+}
+
+// String returns the Postgres connection string with the password redacted,
+// so that the config can be logged safely. A URL that is neither a postgres://
+// URL nor a keyword/value string lib/pq parses is not returned at all.
+func (c DBConfig) String() string {
+	if c.URL == "" {
+		password := ""
+		if c.Password != "" {
+			password = redactedPassword
+		}
+
+		return c.keywordValueDSN(password)
+	}
+
+	if !strings.HasPrefix(c.URL, "postgres://") && !strings.HasPrefix(c.URL, "postgresql://") {
+		return redactKeywordValueDSN(c.URL)
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return ""
+	}
+
+	if query := u.Query(); query.Has("password") {
+		query.Set("password", redactedPassword)
+		u.RawQuery = query.Encode()
+	}
+
+	return u.Redacted()
+}
+
+// redactKeywordValueDSN returns the keyword/value connection string dsn with
+// its password redacted, or an empty string if lib/pq would not parse it.
+// Values are read as lib/pq does: up to a space, or between single quotes,
+// with backslash escapes.
+func redactKeywordValueDSN(dsn string) string {
+	var (
+		parts []string
+		s     = strings.TrimSpace(dsn)
+	)
+
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return ""
+		}
+
+		keyword := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t\n\r")
+
+		value, rest, ok := readDSNValue(s)
+		if !ok || keyword == "" {
+			return ""
+		}
+
+		if keyword == "password" {
+			value = redactedPassword
+		}
+
+		parts = append(parts, keyword+"="+quoteDSNValue(value))
+		s = strings.TrimSpace(rest)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// readDSNValue reads the value s starts with, returning it unescaped and what
+// follows it, or false if a quote or an escape is left open.
+func readDSNValue(s string) (value, rest string, ok bool) {
+	var b strings.Builder
+
+	quoted := strings.HasPrefix(s, "'")
+	if quoted {
+		s = s[1:]
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			if i++; i == len(s) {
+				return "", "", false
+			}
+
+			b.WriteByte(s[i])
+		case quoted && c == '\'':
+			return b.String(), s[i+1:], true
+		case !quoted && strings.IndexByte(" \t\n\r", c) >= 0:
+			return b.String(), s[i:], true
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), "", !quoted
+}
+
+func (c DBConfig) keywordValueDSN(password string) string {
+	var parts []string
+
+	add := func(keyword, value string) {
+		if value != "" {
+			parts = append(parts, keyword+"="+quoteDSNValue(value))
+		}
+	}
+
+	add("host", c.Host)
+	add("port", c.Port)
+	add("user", c.User)
+	add("password", password)
+	add("dbname", c.DBName)
+	add("sslmode", c.SSLMode)
+	add("sslrootcert", c.SSLRootCert)
+	add("sslcert", c.SSLCert)
+	add("sslkey", c.SSLKey)
+	add("application_name", c.ApplicationName)
+	add("search_path", c.SearchPath)
+
+	// connect_timeout takes whole seconds, so it is rounded up to not turn a
+	// short timeout into none.
+	if c.ConnectTimeout > 0 {
+		add("connect_timeout", strconv.Itoa(int(math.Ceil(c.ConnectTimeout.Seconds()))))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue quotes value for a keyword/value connection string when it is
+// empty or holds spaces, quotes or backslashes.
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// GoString redacts the password from %#v as well.
+func (c DBConfig) GoString() string {
+	return fmt.Sprintf("config.DBConfig(%s)", c.String())
+}
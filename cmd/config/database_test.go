@@ -0,0 +1,94 @@
+package config_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"storage/cmd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDBConfigPostgresDSN(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name        string
+		in          config.DBConfig
+		out         string
+		outRedacted string
+	}{
+		{
+			name: "KeywordValue",
+			in: config.DBConfig{
+				Host:     "localhost",
+				Port:     "5432",
+				User:     "cfabrica46",
+				Password: "abcd",
+				DBName:   "storage",
+				SSLMode:  "disable",
+			},
+			out:         "host=localhost port=5432 user=cfabrica46 password=abcd dbname=storage sslmode=disable",
+			outRedacted: "host=localhost port=5432 user=cfabrica46 password=xxxxx dbname=storage sslmode=disable",
+		},
+		{
+			name: "Options",
+			in: config.DBConfig{
+				Host:            "db",
+				Password:        `it's a \secret`,
+				SSLMode:         "verify-full",
+				SSLRootCert:     "/etc/ssl/ca.pem",
+				ApplicationName: "storage",
+				SearchPath:      "users, public",
+				ConnectTimeout:  1500 * time.Millisecond,
+			},
+			out: `host=db password='it\'s a \\secret' sslmode=verify-full sslrootcert=/etc/ssl/ca.pem ` +
+				`application_name=storage search_path='users, public' connect_timeout=2`,
+			outRedacted: `host=db password=xxxxx sslmode=verify-full sslrootcert=/etc/ssl/ca.pem ` +
+				`application_name=storage search_path='users, public' connect_timeout=2`,
+		},
+		{
+			name: "URL",
+			in: config.DBConfig{
+				URL:      "postgres://cfabrica46:abcd@db:5432/storage?sslmode=require",
+				Password: "ignored",
+			},
+			out:         "postgres://cfabrica46:abcd@db:5432/storage?sslmode=require",
+			outRedacted: "postgres://cfabrica46:xxxxx@db:5432/storage?sslmode=require",
+		},
+		{
+			name:        "URLPasswordParameter",
+			in:          config.DBConfig{URL: "postgresql://cfabrica46@db/storage?sslmode=require&password=abcd"},
+			out:         "postgresql://cfabrica46@db/storage?sslmode=require&password=abcd",
+			outRedacted: "postgresql://cfabrica46@db/storage?password=xxxxx&sslmode=require",
+		},
+		{
+			name:        "URLKeywordValue",
+			in:          config.DBConfig{URL: "host=db user=cfabrica46 password=abcd dbname=storage"},
+			out:         "host=db user=cfabrica46 password=abcd dbname=storage",
+			outRedacted: "host=db user=cfabrica46 password=xxxxx dbname=storage",
+		},
+		{
+			name:        "URLKeywordValueQuoted",
+			in:          config.DBConfig{URL: `host = db  password='ab\'cd abcd' search_path='users, public'`},
+			out:         `host = db  password='ab\'cd abcd' search_path='users, public'`,
+			outRedacted: `host=db password=xxxxx search_path='users, public'`,
+		},
+		{
+			name:        "URLKeywordValueUnterminated",
+			in:          config.DBConfig{URL: "host=db password='abcd"},
+			out:         "host=db password='abcd",
+			outRedacted: "",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.out, tt.in.PostgresDSN())
+			assert.Equal(t, tt.outRedacted, tt.in.String())
+			assert.NotContains(t, fmt.Sprintf("%v %+v %#v", tt.in, tt.in, tt.in), "abcd")
+		})
+	}
+}
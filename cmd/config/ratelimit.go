@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitConfig holds the limit of every rate limited endpoint.
+type RateLimitConfig struct {
+	// Limits maps the name of a service method, e.g. GetAllUsers, to its
+	// limit.
+	Limits map[string]RateLimit
+	// TrustedProxies are the addresses whose X-Forwarded-For header, or
+	// x-forwarded-for metadata over gRPC, tells the address of the client.
+	TrustedProxies []netip.Prefix
+}
+
+// RateLimit lets every client make Events requests Per period, and up to
+// Burst at once.
+type RateLimit struct {
+	Events int
+	Per    time.Duration
+	Burst  int
+}
+
+var (
+	ErrInvalidRateLimit      = errors.New("invalid rate_limits")
+	ErrInvalidTrustedProxies = errors.New("invalid trusted_proxies")
+)
+
+// ParseRateLimits parses a comma-separated list of Method=N/period[:burst]
+// limits, e.g. "GetAllUsers=20/s:40,GetUserByUsernameAndPassword=5/m".
+// The period is a duration, its 1 being optional, and burst defaults to N.
+func ParseRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		method, spec, ok := strings.Cut(entry, "=")
+		if !ok || method == "" {
+			return nil, fmt.Errorf("%w: %q is not Method=N/period[:burst]", ErrInvalidRateLimit, entry)
+		}
+
+		limit, err := parseRateLimit(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRateLimit, method, err)
+		}
+
+		limits[method] = limit
+	}
+
+	return limits, nil
+}
+
+// parseRateLimit parses N/period[:burst].
+func parseRateLimit(spec string) (limit RateLimit, err error) {
+	spec, burst, hasBurst := strings.Cut(spec, ":")
+
+	events, period, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("%q is not N/period", spec)
+	}
+
+	limit.Events, err = strconv.Atoi(events)
+	if err != nil || limit.Events < 1 {
+		return RateLimit{}, fmt.Errorf("%q is not a positive number of requests", events)
+	}
+
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+
+	limit.Per, err = time.ParseDuration(period)
+	if err != nil || limit.Per <= 0 {
+		return RateLimit{}, fmt.Errorf("%q is not a positive period", period)
+	}
+
+	limit.Burst = limit.Events
+
+	if hasBurst {
+		limit.Burst, err = strconv.Atoi(burst)
+		if err != nil || limit.Burst < 1 {
+			return RateLimit{}, fmt.Errorf("%q is not a positive burst", burst)
+		}
+	}
+
+	return limit, nil
+}
+
+// ParseTrustedProxies parses a comma-separated list of CIDRs or bare IP
+// addresses, e.g. "10.0.0.0/8,192.168.1.10".
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidTrustedProxies, err)
+			}
+
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTrustedProxies, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
@@ -0,0 +1,123 @@
+package config_test
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"storage/cmd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		out    map[string]config.RateLimit
+		name   string
+		in     string
+		outErr string
+	}{
+		{
+			name: "Empty",
+			in:   "",
+			out:  map[string]config.RateLimit{},
+		},
+		{
+			name: "Several",
+			in:   "GetAllUsers=20/s:40, GetUserByUsernameAndPassword=5/m",
+			out: map[string]config.RateLimit{
+				"GetAllUsers":                  {Events: 20, Per: time.Second, Burst: 40},
+				"GetUserByUsernameAndPassword": {Events: 5, Per: time.Minute, Burst: 5},
+			},
+		},
+		{
+			name: "Period",
+			in:   "InsertUser=100/10m",
+			out: map[string]config.RateLimit{
+				"InsertUser": {Events: 100, Per: 10 * time.Minute, Burst: 100},
+			},
+		},
+		{
+			name:   "NoMethod",
+			in:     "=5/s",
+			outErr: config.ErrInvalidRateLimit.Error(),
+		},
+		{
+			name:   "NoPeriod",
+			in:     "GetAllUsers=5",
+			outErr: config.ErrInvalidRateLimit.Error(),
+		},
+		{
+			name:   "InvalidEvents",
+			in:     "GetAllUsers=0/s",
+			outErr: "not a positive number of requests",
+		},
+		{
+			name:   "InvalidPeriod",
+			in:     "GetAllUsers=5/fortnight",
+			outErr: "not a positive period",
+		},
+		{
+			name:   "InvalidBurst",
+			in:     "GetAllUsers=5/s:x",
+			outErr: "not a positive burst",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			limits, err := config.ParseRateLimits(tt.in)
+			if tt.outErr == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.out, limits)
+			} else {
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		in     string
+		outErr string
+		out    []netip.Prefix
+	}{
+		{name: "Empty", in: ""},
+		{
+			name: "Several",
+			in:   "10.0.0.0/8, 192.168.1.10, 2001:db8::/32",
+			out: []netip.Prefix{
+				netip.MustParsePrefix("10.0.0.0/8"),
+				netip.MustParsePrefix("192.168.1.10/32"),
+				netip.MustParsePrefix("2001:db8::/32"),
+			},
+		},
+		{
+			name: "HostBits",
+			in:   "10.1.2.3/8",
+			out:  []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		},
+		{name: "InvalidAddr", in: "proxy", outErr: config.ErrInvalidTrustedProxies.Error()},
+		{name: "InvalidPrefix", in: "10.0.0.0/33", outErr: config.ErrInvalidTrustedProxies.Error()},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			prefixes, err := config.ParseTrustedProxies(tt.in)
+			if tt.outErr == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.out, prefixes)
+			} else {
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
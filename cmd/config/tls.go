@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TLSConfig holds the PEM files the API and gRPC servers serve TLS with.
+// Without CertFile, they serve plaintext.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, if not empty, turns on mutual TLS: clients must present
+	// a certificate signed by one of its CAs.
+	ClientCAFile string
+}
+
+var ErrInvalidTLSConfig = errors.New("invalid TLS config")
+
+// Enabled reports whether TLS is served.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Validate checks that the certificate and key come together, and that
+// client certificates are only asked for over TLS.
+func (c TLSConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("%w: tls_cert_file and tls_key_file go together", ErrInvalidTLSConfig)
+	}
+
+	if c.ClientCAFile != "" && !c.Enabled() {
+		return fmt.Errorf("%w: tls_client_ca_file needs tls_cert_file", ErrInvalidTLSConfig)
+	}
+
+	return nil
+}
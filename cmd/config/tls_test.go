@@ -0,0 +1,45 @@
+package config_test
+
+import (
+	"testing"
+
+	"storage/cmd/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name       string
+		in         config.TLSConfig
+		outErr     bool
+		outEnabled bool
+	}{
+		{name: "Plaintext", in: config.TLSConfig{}},
+		{name: "TLS", in: config.TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"}, outEnabled: true},
+		{
+			name:       "MutualTLS",
+			in:         config.TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", ClientCAFile: "ca.crt"},
+			outEnabled: true,
+		},
+		{name: "NoKey", in: config.TLSConfig{CertFile: "tls.crt"}, outErr: true, outEnabled: true},
+		{name: "NoCert", in: config.TLSConfig{KeyFile: "tls.key"}, outErr: true},
+		{name: "ClientCAWithoutTLS", in: config.TLSConfig{ClientCAFile: "ca.crt"}, outErr: true},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.in.Validate()
+			if tt.outErr {
+				assert.ErrorIs(t, err, config.ErrInvalidTLSConfig)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			assert.Equal(t, tt.outEnabled, tt.in.Enabled())
+		})
+	}
+}
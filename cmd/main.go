@@ -1,110 +1,552 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"storage/cmd/config"
+	"storage/internal/auth"
 	"storage/internal/endpoint"
-	"storage/internal/entity"
+	"storage/internal/grpctransport"
+	"storage/internal/health"
+	"storage/internal/metrics"
+	"storage/internal/migrate"
+	"storage/internal/password"
+	"storage/internal/pb"
+	"storage/internal/ratelimit"
+	"storage/internal/repository"
 	"storage/internal/service"
+	"storage/internal/tlsconfig"
 	"storage/internal/transport"
 
-	httptransport "github.com/go-kit/kit/transport/http"
+	kitlog "github.com/go-kit/log"
 	"github.com/gorilla/mux"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
+// userRepository stores the users, their login attempts and the API keys of
+// the callers.
+type userRepository interface {
+	service.UserRepository
+	service.LoginAttemptRepository
+	auth.KeyRepository
+}
+
+// telemetry holds where the server reports what it does.
+type telemetry struct {
+	logger         kitlog.Logger
+	tracerProvider trace.TracerProvider
+}
+
+var (
+	ErrUnknownPasswordHasher = errors.New("unknown password hasher")
+	ErrUnknownStorageBackend = errors.New("unknown storage backend")
+	ErrUnknownDatabaseDriver = errors.New("unknown database driver")
+	ErrUnknownLogFormat      = errors.New("unknown log format")
+	ErrAuthNeedsDatabase     = errors.New("auth_required needs the database storage backend to keep the API keys")
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	cfg, err := config.GetAPIConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	db, err := openPostgresConn(cfg.DBConfig)
+	logger, err := newLogger(cfg.LogFormat)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 
-	runServer(cfg.Port, db)
-}
+	// What is still logged through the standard logger goes through logger
+	// too, with its caller.
+	log.SetFlags(log.Lshortfile)
+	log.SetOutput(kitlog.NewStdlibAdapter(logger))
 
-func runServer(port string, db *sql.DB) {
-	svc := service.GetService(db)
+	if args := pflag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err = runMigrate(cfg.DBConfig, args[1:]); err != nil {
+			log.Fatal(err)
+		}
 
-	getAllUsersHandler := httptransport.NewServer(
-		endpoint.MakeGetAllUsersEndpoint(svc),
-		transport.DecodeRequestWithoutBody(),
-		transport.EncodeResponse,
-	)
+		return
+	}
 
-	getUserByIDHandler := httptransport.NewServer(
-		endpoint.MakeGetUserByIDEndpoint(svc),
-		transport.DecodeRequest(entity.IDRequest{}),
-		transport.EncodeResponse,
-	)
+	if args := pflag.Args(); len(args) > 0 && args[0] == "apikey" {
+		if err = runAPIKey(cfg.DBConfig, args[1:]); err != nil {
+			log.Fatal(err)
+		}
 
-	getUserByUsernameAndPasswordHandler := httptransport.NewServer(
-		endpoint.MakeGetUserByUsernameAndPasswordEndpoint(svc),
-		transport.DecodeRequest(entity.UsernamePasswordRequest{}),
-		transport.EncodeResponse,
-	)
+		return
+	}
 
-	getIDByUsernameHandler := httptransport.NewServer(
-		endpoint.MakeGetIDByUsernameEndpoint(svc),
-		transport.DecodeRequest(entity.UsernameRequest{}),
-		transport.EncodeResponse,
-	)
+	if cfg.AuthRequired && cfg.StorageBackend == "memory" {
+		log.Fatal(ErrAuthNeedsDatabase)
+	}
+
+	if !cfg.AuthRequired {
+		log.Println("warning: auth_required is off, every endpoint is served to callers without an API key")
+	}
+
+	tp, shutdownTracing, err := newTracerProvider(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownGracePeriod)
+		defer cancel()
+
+		if err = shutdownTracing(ctx); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	hasher, err := newPasswordHasher(cfg.PasswordHasher)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	repo, db, err := newUserRepository(cfg, tp)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var migrator *migrate.Migrator
+
+	if db != nil {
+		defer db.Close()
+
+		migrator, err = newMigrator(cfg, db)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err = metrics.RegisterDBStats(db, cfg.DBConfig.Driver); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	lockout := service.LockoutPolicy{
+		Threshold:     cfg.Lockout.Threshold,
+		Duration:      cfg.Lockout.Duration,
+		MaxDuration:   cfg.Lockout.MaxDuration,
+		FailureWindow: cfg.Lockout.FailureWindow,
+	}
+
+	svc := service.TracingMiddleware(tp)(service.GetService(repo, hasher).WithLockout(repo, lockout))
+	checker := health.NewChecker(db, migrator, version, kitlog.With(logger, "layer", "health"))
 
-	insertUserHandler := httptransport.NewServer(
-		endpoint.MakeInsertUserEndpoint(svc),
-		transport.DecodeRequest(entity.UsernamePasswordEmailRequest{}),
-		transport.EncodeResponse,
+	tel := telemetry{logger: logger, tracerProvider: tp}
+
+	var authenticator *auth.Authenticator
+	if cfg.AuthRequired {
+		authenticator = auth.NewAuthenticator(repo)
+	}
+
+	endpoints, err := newEndpoints(cfg, tel, svc, authenticator)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+
+	var tlsConfig *tls.Config
+
+	if cfg.TLS.Enabled() {
+		tlsConfig, err = newTLSConfig(watchCtx, cfg.TLS)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = runServer(
+		cfg,
+		newHTTPServer(cfg, newHandler(cfg, tel, endpoints, checker), tlsConfig),
+		newGRPCServer(cfg, tel, endpoints, tlsConfig),
+		checker,
 	)
+	if err != nil {
+		log.Println(err)
+	}
+}
 
-	deleteUserHandler := httptransport.NewServer(
-		endpoint.MakeDeleteUserEndpoint(svc),
-		transport.DecodeRequest(entity.IDRequest{}),
-		transport.EncodeResponse,
+// newEndpoints returns the endpoints of svc, restricted to the API keys of
+// authenticator unless it is nil, rate limited as rate_limits says, and
+// logged, measured and traced through tel, which both the HTTP and the gRPC
+// transports serve.
+func newEndpoints(
+	cfg *config.APIConfig,
+	tel telemetry,
+	svc service.Service,
+	authenticator *auth.Authenticator,
+) (endpoint.Endpoints, error) {
+	limiters := make(map[string]*ratelimit.Limiter, len(cfg.RateLimit.Limits))
+	for method, limit := range cfg.RateLimit.Limits {
+		limiters[method] = ratelimit.New(ratelimit.Limit{
+			Rate:  rate.Limit(float64(limit.Events) / limit.Per.Seconds()),
+			Burst: limit.Burst,
+		})
+	}
+
+	endpoints := endpoint.LogEndpoints(
+		endpoint.MakeEndpoints(svc, endpoint.TimeoutMiddleware(cfg.Timeout)),
+		kitlog.With(tel.logger, "layer", "endpoint"),
 	)
 
-	router := mux.NewRouter()
-	router.Methods(http.MethodGet).Path("/users").Handler(getAllUsersHandler)
-	router.Methods(http.MethodGet).Path("/user/id").Handler(getUserByIDHandler)
-	router.Methods(http.MethodGet).Path("/user/username_password").
-		Handler(getUserByUsernameAndPasswordHandler)
-	router.Methods(http.MethodGet).Path("/id/username").Handler(getIDByUsernameHandler)
-	router.Methods(http.MethodPost).Path("/user").Handler(insertUserHandler)
-	router.Methods(http.MethodDelete).Path("/user").Handler(deleteUserHandler)
-
-	log.Println("ListenAndServe on localhost:" + os.Getenv("PORT"))
-	log.Println(http.ListenAndServe(":"+port, router))
+	if authenticator != nil {
+		endpoints = endpoint.AuthorizeEndpoints(endpoints)
+	}
+
+	// Rate limiting goes between authentication, which tells the clients
+	// apart by their API key, and authorization, so that it also holds back
+	// the clients guessing API keys or passwords, by their IP address.
+	endpoints, err := endpoint.RateLimitEndpoints(endpoints, limiters)
+	if err != nil {
+		return endpoint.Endpoints{}, fmt.Errorf("invalid rate_limits: %w", err)
+	}
+
+	if authenticator != nil {
+		endpoints = endpoint.AuthenticateEndpoints(endpoints, authenticator)
+	}
+
+	return endpoint.InstrumentEndpoints(
+		endpoint.TraceEndpoints(endpoints, tel.tracerProvider),
+		metrics.NewEndpointMetrics(),
+	), nil
 }
 
-func openPostgresConn(conn config.DBConfig) (*sql.DB, error) {
-	psqlInfo := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		conn.Host,
-		conn.Port,
-		conn.User,
-		conn.Password,
-		conn.DBName,
-	)
+// newHandler returns the router of every API and health route, logged and
+// traced through tel.
+func newHandler(
+	cfg *config.APIConfig,
+	tel telemetry,
+	endpoints endpoint.Endpoints,
+	checker *health.Checker,
+) http.Handler {
+	router := mux.NewRouter()
+
+	// Every route, present or future, hangs from api so that it honors the
+	// configured uri_prefix.
+	api := router
+	if cfg.URIPrefix != "" {
+		api = router.PathPrefix(cfg.URIPrefix).Subrouter()
+	}
 
-	db, err := sql.Open("postgres", psqlInfo)
+	// Tracing goes first so that the span is in the context of what the
+	// logging options log.
+	options := transport.TracingOptions(tel.tracerProvider)
+	options = append(options, transport.LoggingOptions(kitlog.With(tel.logger, "layer", "transport"))...)
+	options = append(options, transport.RateLimitOptions(cfg.RateLimit.TrustedProxies)...)
+
+	transport.RegisterRoutes(api, endpoints, options...)
+	checker.RegisterRoutes(api)
+
+	return router
+}
+
+// newTLSConfig returns the TLS configuration of the servers. The certificate
+// files are reloaded whenever they change until ctx is done.
+func newTLSConfig(ctx context.Context, cfg config.TLSConfig) (*tls.Config, error) {
+	reloader, err := tlsconfig.New(tlsconfig.Files{
+		CertFile:     cfg.CertFile,
+		KeyFile:      cfg.KeyFile,
+		ClientCAFile: cfg.ClientCAFile,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	go func() {
+		// A failed reload keeps the certificates loaded last.
+		watchErr := reloader.Watch(ctx, func(err error) {
+			if err != nil {
+				log.Println(err)
+			} else {
+				log.Println("reloaded TLS certificates")
+			}
+		})
+		if watchErr != nil {
+			log.Println(watchErr)
+		}
+	}()
+
+	return reloader.Config(), nil
+}
+
+// newHTTPServer returns the API server of handler, serving TLS with
+// tlsConfig unless it is nil.
+func newHTTPServer(cfg *config.APIConfig, handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Addr:              config.ListenAddr(cfg.Port),
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+}
+
+// newGRPCServer returns the gRPC server of endpoints, logged and traced
+// through tel and serving TLS with tlsConfig unless it is nil.
+func newGRPCServer(
+	cfg *config.APIConfig,
+	tel telemetry,
+	endpoints endpoint.Endpoints,
+	tlsConfig *tls.Config,
+) *grpc.Server {
+	var serverOptions []grpc.ServerOption
+	if tlsConfig != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	options := grpctransport.TracingOptions(tel.tracerProvider)
+	options = append(options, grpctransport.LoggingOptions(kitlog.With(tel.logger, "layer", "transport"))...)
+	options = append(options, grpctransport.RateLimitOptions(cfg.RateLimit.TrustedProxies)...)
+
+	server := grpc.NewServer(serverOptions...)
+	pb.RegisterStorageServer(server, grpctransport.NewServer(endpoints, options...))
+
+	return server
+}
+
+// runServer serves server until SIGINT or SIGTERM arrives, then fails the
+// readiness of checker, waits shutdown_delay and drains the connections
+// within shutdown_grace_period. The gRPC server, if grpc_port is set, drains
+// along with the API server, and the metrics, if enabled, are served on
+// their own port, always in plaintext, until both stop. The caller closes
+// the database afterwards.
+func runServer(cfg *config.APIConfig, server *http.Server, grpcServer *grpc.Server, checker *health.Checker) error {
+	var grpcListener net.Listener
+
+	if cfg.GRPCPort != "" {
+		var err error
+
+		grpcListener, err = net.Listen("tcp", config.ListenAddr(cfg.GRPCPort))
+		if err != nil {
+			return fmt.Errorf("error to listen for gRPC: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 3)
+
+	go func() {
+		if server.TLSConfig != nil {
+			log.Println("ListenAndServeTLS on localhost" + server.Addr + cfg.URIPrefix)
+
+			serveErr <- server.ListenAndServeTLS("", "")
+
+			return
+		}
+
+		log.Println("ListenAndServe on localhost" + server.Addr + cfg.URIPrefix)
+
+		serveErr <- server.ListenAndServe()
+	}()
+
+	if grpcListener != nil {
+		go func() {
+			log.Println("serving gRPC on localhost" + config.ListenAddr(cfg.GRPCPort))
+
+			serveErr <- grpcServer.Serve(grpcListener)
+		}()
+	}
+
+	var metricsServer *http.Server
+
+	if cfg.MetricsPort != "" {
+		metricsServer = &http.Server{
+			Addr:              config.ListenAddr(cfg.MetricsPort),
+			Handler:           metrics.Handler(),
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		}
+
+		go func() {
+			log.Println("serving metrics on localhost" + metricsServer.Addr + "/metrics")
+
+			serveErr <- metricsServer.ListenAndServe()
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// A second signal kills the process right away.
+	stop()
+
+	log.Println("shutting down")
+
+	checker.SetDraining()
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownGracePeriod)
+	defer cancel()
+
+	grpcStopped := make(chan struct{})
+
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error to shut down: %w", err)
+	}
+
+	// The calls still running once the grace period is over are cancelled.
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+
+		return fmt.Errorf("error to shut down gRPC: %w", shutdownCtx.Err())
+	}
+
+	// Scrapes keep working while the API drains.
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("error to shut down metrics: %w", err)
+		}
+	}
+
+	log.Println("server stopped")
+
+	return nil
+}
+
+func newLogger(format string) (kitlog.Logger, error) {
+	var logger kitlog.Logger
+
+	switch format {
+	case "logfmt":
+		logger = kitlog.NewLogfmtLogger(kitlog.NewSyncWriter(os.Stderr))
+	case "json":
+		logger = kitlog.NewJSONLogger(kitlog.NewSyncWriter(os.Stderr))
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownLogFormat, format)
+	}
+
+	return kitlog.With(logger, "ts", kitlog.DefaultTimestampUTC), nil
+}
+
+// newPasswordHasher returns a hasher that hashes with the algorithm of the
+// given name and verifies the hashes of every algorithm, so that changing
+// password_hasher does not break the existing users.
+func newPasswordHasher(name string) (service.PasswordHasher, error) {
+	argon2id := password.NewArgon2id(password.DefaultArgon2idParams)
+	bcryptHasher := password.NewBcrypt(bcrypt.DefaultCost)
+
+	switch name {
+	case "argon2id":
+		return password.NewMulti(argon2id, bcryptHasher), nil
+	case "bcrypt":
+		return password.NewMulti(bcryptHasher, argon2id), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPasswordHasher, name)
+	}
+}
+
+// newUserRepository returns the repository selected by storage_backend, which
+// also keeps the login attempts, and its database, which is nil for the
+// memory backend.
+func newUserRepository(cfg *config.APIConfig, tp trace.TracerProvider) (userRepository, *sql.DB, error) {
+	switch cfg.StorageBackend {
+	case "database":
+		db, err := openDB(cfg.DBConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if cfg.DBConfig.Driver == "sqlite" {
+			return repository.NewSQLite(db).WithTracerProvider(tp), db, nil
+		}
+
+		return repository.NewPostgres(db).WithTracerProvider(tp), db, nil
+	case "memory":
+		return repository.NewMemory(), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownStorageBackend, cfg.StorageBackend)
+	}
+}
+
+func openDB(conn config.DBConfig) (*sql.DB, error) {
+	switch conn.Driver {
+	case "postgres":
+		return openPostgresConn(conn)
+	case "sqlite":
+		return openSQLiteConn(conn)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDatabaseDriver, conn.Driver)
+	}
+}
+
+// openPostgresConn connects to Postgres. Only the redacted connection string
+// of conn, its String, may ever be logged.
+func openPostgresConn(conn config.DBConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", conn.PostgresDSN())
+	if err != nil {
+		return nil, fmt.Errorf("error to open postgres (%s): %w", conn, err)
+	}
+
+	db.SetMaxOpenConns(conn.Pool.MaxOpenConns)
+	db.SetMaxIdleConns(conn.Pool.MaxIdleConns)
+	db.SetConnMaxLifetime(conn.Pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(conn.Pool.ConnMaxIdleTime)
+
 	err = db.Ping()
+	if err != nil {
+		db.Close()
+
+		return nil, fmt.Errorf("error to connect to postgres (%s): %w", conn, err)
+	}
+
+	log.Printf("connected to postgres (%s)", conn)
+
+	return db, nil
+}
+
+func openSQLiteConn(conn config.DBConfig) (*sql.DB, error) {
+	// LIKE is made case sensitive to match Postgres in the username filter,
+	// and transactions take the write lock upfront so that concurrent
+	// migrators wait for each other.
+	db, err := sql.Open(
+		"sqlite",
+		conn.Path+"?_pragma=case_sensitive_like(1)&_pragma=busy_timeout(5000)&_txlock=immediate",
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	// SQLite allows a single writer, and every connection to :memory: opens
+	// a database of its own, so the pool settings do not apply.
+	db.SetMaxOpenConns(1)
+
 	return db, nil
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"storage/cmd/config"
+	"storage/internal/migrate"
+)
+
+var ErrUnknownMigrateCommand = errors.New("unknown migrate command, use up, down or status")
+
+// runMigrate runs "migrate up", "migrate down" or "migrate status" against
+// the configured database.
+func runMigrate(conn config.DBConfig, args []string) error {
+	if len(args) != 1 {
+		return ErrUnknownMigrateCommand
+	}
+
+	db, err := openDB(conn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrate.New(db, conn.Driver)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		var applied []migrate.Migration
+
+		applied, err = migrator.Up(ctx)
+		for _, migration := range applied {
+			log.Printf("applied %04d_%s", migration.Version, migration.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			log.Println("no pending migrations")
+		}
+	case "down":
+		var reverted *migrate.Migration
+
+		reverted, err = migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+
+		if reverted == nil {
+			log.Println("no applied migrations")
+		} else {
+			log.Printf("reverted %04d_%s", reverted.Version, reverted.Name)
+		}
+	case "status":
+		var statuses []migrate.Status
+
+		statuses, err = migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownMigrateCommand, args[0])
+	}
+
+	return nil
+}
+
+// newMigrator returns the migrator of the configured database, applying the
+// pending migrations first when auto_migrate is set.
+func newMigrator(cfg *config.APIConfig, db *sql.DB) (*migrate.Migrator, error) {
+	migrator, err := migrate.New(db, cfg.DBConfig.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	// A :memory: database starts empty every time, so it always needs the
+	// migrations.
+	inMemory := cfg.DBConfig.Driver == "sqlite" && cfg.DBConfig.Path == ":memory:"
+	if !cfg.AutoMigrate && !inMemory {
+		return migrator, nil
+	}
+
+	applied, err := migrator.Up(context.Background())
+	for _, migration := range applied {
+		log.Printf("applied %04d_%s", migration.Version, migration.Name)
+	}
+
+	return migrator, err
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"storage/cmd/config"
+	"storage/internal/requestid"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrUnknownTraceExporter = errors.New("unknown trace exporter")
+
+// newTracerProvider returns the TracerProvider of trace_exporter and the
+// function that flushes its pending spans on shutdown. With "none" nothing is
+// traced. The OTLP exporter sends spans over HTTP and is configured with the
+// standard OTEL_EXPORTER_OTLP_* variables.
+func newTracerProvider(
+	ctx context.Context,
+	cfg config.TracingConfig,
+) (tp trace.TracerProvider, shutdown func(context.Context) error, err error) {
+	var exporter sdktrace.SpanExporter
+
+	closeFile := func() error { return nil }
+
+	switch cfg.Exporter {
+	case "none":
+		return trace.NewNoopTracerProvider(), func(context.Context) error { return nil }, nil
+	case "stdout":
+		var w io.Writer = os.Stdout
+
+		if cfg.File != "" {
+			var file *os.File
+
+			file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error to open trace_file: %w", err)
+			}
+
+			w, closeFile = file, file.Close
+		}
+
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownTraceExporter, cfg.Exporter)
+	}
+
+	if err != nil {
+		_ = closeFile()
+
+		return nil, nil, fmt.Errorf("error to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(requestid.SpanProcessor{}),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String("storage"),
+			semconv.ServiceVersionKey.String(version),
+		)),
+	)
+
+	// Libraries that trace through the global API join the same traces.
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider, func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeErr := closeFile(); err == nil {
+			err = closeErr
+		}
+
+		return err
+	}, nil
+}
@@ -0,0 +1,206 @@
+// Package auth authenticates the callers of the API by their API key and
+// authorizes their calls by the scopes the key was granted. Only the SHA-256
+// hash of every key is stored. Callers presenting a client certificate over
+// mutual TLS are also known by its Identity.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"storage/internal/entity"
+)
+
+// KeyRepository persists the API keys by the hash of their secret.
+// Implementations report a missing key with ErrKeyNotFound and a duplicate
+// name with ErrKeyNameTaken.
+type KeyRepository interface {
+	InsertAPIKey(ctx context.Context, key entity.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (entity.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]entity.APIKey, error)
+	DeleteAPIKey(ctx context.Context, name string) (rowsAffected int, err error)
+}
+
+// Authenticator checks the API key of every call against a KeyRepository.
+type Authenticator struct {
+	keys KeyRepository
+}
+
+// secretContextKey is the context key of the API key sent by the caller.
+type secretContextKey struct{}
+
+// apiKeyContextKey is the context key of the API key of the caller once
+// authenticated.
+type apiKeyContextKey struct{}
+
+// Scopes an API key can be granted. ScopeAdmin grants every other scope.
+const (
+	ScopeRead              = "read"
+	ScopeWrite             = "write"
+	ScopeVerifyCredentials = "verify-credentials"
+	ScopeAdmin             = "admin"
+)
+
+// Header is the header, or metadata key in lower case, callers send their
+// API key in. A bearer token in the Authorization header is accepted too.
+const Header = "X-API-Key"
+
+// secretPrefix starts every generated API key, so that they are easy to
+// recognize in configuration files and secret scanners.
+const secretPrefix = "sk_"
+
+// secretBytes is how many random bytes make up an API key.
+const secretBytes = 32
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid API key")
+	ErrForbidden       = errors.New("API key lacks the required scope")
+	ErrKeyNotFound     = errors.New("API key not found")
+	ErrKeyNameTaken    = errors.New("API key name already taken")
+	ErrUnknownScope    = errors.New("unknown scope")
+)
+
+// scopes holds every valid scope.
+var scopes = map[string]bool{
+	ScopeRead:              true,
+	ScopeWrite:             true,
+	ScopeVerifyCredentials: true,
+	ScopeAdmin:             true,
+}
+
+// NewAuthenticator ...
+func NewAuthenticator(keys KeyRepository) *Authenticator {
+	return &Authenticator{keys: keys}
+}
+
+// Authenticate returns the API key whose secret is given. It fails with
+// ErrUnauthenticated for an empty or unknown secret.
+func (a *Authenticator) Authenticate(ctx context.Context, secret string) (entity.APIKey, error) {
+	if secret == "" {
+		return entity.APIKey{}, ErrUnauthenticated
+	}
+
+	key, err := a.keys.GetAPIKeyByHash(ctx, Hash(secret))
+	if err != nil {
+		if errors.Is(err, ErrKeyNotFound) {
+			return entity.APIKey{}, ErrUnauthenticated
+		}
+
+		return entity.APIKey{}, fmt.Errorf("error to authenticate: %w", err)
+	}
+
+	return key, nil
+}
+
+// Authorize returns the API key whose secret is given if it holds scope. It
+// fails as Authenticate does, and with ErrForbidden for a key without scope.
+func (a *Authenticator) Authorize(ctx context.Context, secret, scope string) (entity.APIKey, error) {
+	key, err := a.Authenticate(ctx, secret)
+	if err != nil {
+		return entity.APIKey{}, err
+	}
+
+	if !HasScope(key, scope) {
+		return entity.APIKey{}, fmt.Errorf("%w: %q", ErrForbidden, scope)
+	}
+
+	return key, nil
+}
+
+// HasScope reports whether key was granted scope, or ScopeAdmin.
+func HasScope(key entity.APIKey, scope string) bool {
+	for _, granted := range key.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseScopes parses a comma separated list of scopes, dropping duplicates.
+func ParseScopes(s string) ([]string, error) {
+	var parsed []string
+
+	seen := make(map[string]bool)
+
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.TrimSpace(scope)
+		if !scopes[scope] {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownScope, scope)
+		}
+
+		if !seen[scope] {
+			seen[scope] = true
+			parsed = append(parsed, scope)
+		}
+	}
+
+	return parsed, nil
+}
+
+// GenerateSecret returns a new random API key.
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error to generate API key: %w", err)
+	}
+
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the hex encoded SHA-256 hash of secret, under which its key is
+// stored. Keys are random and long, so a fast hash is enough to keep them
+// safe and lets them be looked up by it.
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Secret returns the API key of a request with the given X-API-Key and
+// Authorization headers, or "" if it has none.
+func Secret(apiKey, authorization string) string {
+	if apiKey != "" {
+		return apiKey
+	}
+
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+// NewContext returns a copy of ctx holding the API key sent by the caller.
+func NewContext(ctx context.Context, secret string) context.Context {
+	return context.WithValue(ctx, secretContextKey{}, secret)
+}
+
+// FromContext returns the API key sent by the caller, or "" if it sent none.
+func FromContext(ctx context.Context) string {
+	secret, _ := ctx.Value(secretContextKey{}).(string)
+
+	return secret
+}
+
+// NewAPIKeyContext returns a copy of ctx holding the API key the caller was
+// authenticated by.
+func NewAPIKeyContext(ctx context.Context, key entity.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext returns the API key the caller was authenticated by, and
+// false if it was not.
+func APIKeyFromContext(ctx context.Context) (entity.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(entity.APIKey)
+
+	return key, ok
+}
@@ -0,0 +1,144 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingKeys is an auth.KeyRepository whose database is closed.
+type failingKeys struct {
+	auth.KeyRepository
+}
+
+const (
+	readerSecret = "sk_reader"
+	adminSecret  = "sk_admin"
+)
+
+// GetAPIKeyByHash ...
+func (failingKeys) GetAPIKeyByHash(context.Context, string) (entity.APIKey, error) {
+	return entity.APIKey{}, errors.New(mock.ErrDatabaseClosed)
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+
+	keys := repository.NewMemory()
+
+	for _, key := range []entity.APIKey{
+		{Name: "reader", Hash: auth.Hash(readerSecret), Scopes: []string{auth.ScopeRead}},
+		{Name: "admin", Hash: auth.Hash(adminSecret), Scopes: []string{auth.ScopeAdmin}},
+	} {
+		assert.Nil(t, keys.InsertAPIKey(context.TODO(), key))
+	}
+
+	for _, tt := range []struct {
+		inKeys  auth.KeyRepository
+		name    string
+		inScope string
+		inKey   string
+		outName string
+		outErr  error
+	}{
+		{name: mock.NameNoError, inKeys: keys, inKey: readerSecret, inScope: auth.ScopeRead, outName: "reader"},
+		{name: "Admin", inKeys: keys, inKey: adminSecret, inScope: auth.ScopeWrite, outName: "admin"},
+		{name: "NoKey", inKeys: keys, inScope: auth.ScopeRead, outErr: auth.ErrUnauthenticated},
+		{name: "UnknownKey", inKeys: keys, inKey: "sk_other", inScope: auth.ScopeRead, outErr: auth.ErrUnauthenticated},
+		{name: "MissingScope", inKeys: keys, inKey: readerSecret, inScope: auth.ScopeWrite, outErr: auth.ErrForbidden},
+		{name: mock.NameErrorDBClosed, inKeys: failingKeys{}, inKey: readerSecret, inScope: auth.ScopeRead},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			key, err := auth.NewAuthenticator(tt.inKeys).Authorize(context.TODO(), tt.inKey, tt.inScope)
+
+			switch {
+			case tt.name == mock.NameErrorDBClosed:
+				assert.ErrorContains(t, err, mock.ErrDatabaseClosed)
+				assert.NotErrorIs(t, err, auth.ErrUnauthenticated)
+			case tt.outErr != nil:
+				assert.ErrorIs(t, err, tt.outErr)
+			default:
+				assert.Nil(t, err)
+				assert.Equal(t, tt.outName, key.Name)
+			}
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		in     string
+		outErr error
+		out    []string
+	}{
+		{name: "One", in: "read", out: []string{auth.ScopeRead}},
+		{
+			name: "Several",
+			in:   "read, write,verify-credentials,read",
+			out:  []string{auth.ScopeRead, auth.ScopeWrite, auth.ScopeVerifyCredentials},
+		},
+		{name: "Unknown", in: "read,delete", outErr: auth.ErrUnknownScope},
+		{name: "Empty", in: "", outErr: auth.ErrUnknownScope},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			scopes, err := auth.ParseScopes(tt.in)
+			assert.ErrorIs(t, err, tt.outErr)
+			assert.Equal(t, tt.out, scopes)
+		})
+	}
+}
+
+func TestSecret(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name            string
+		inAPIKey        string
+		inAuthorization string
+		out             string
+	}{
+		{name: "APIKey", inAPIKey: "sk_a", inAuthorization: "Bearer sk_b", out: "sk_a"},
+		{name: "Bearer", inAuthorization: "bearer sk_b", out: "sk_b"},
+		{name: "Basic", inAuthorization: "Basic dXNlcjpwYXNz", out: ""},
+		{name: "None", out: ""},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.out, auth.Secret(tt.inAPIKey, tt.inAuthorization))
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	t.Parallel()
+
+	secret, err := auth.GenerateSecret()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(secret, "sk_"))
+
+	other, err := auth.GenerateSecret()
+	assert.Nil(t, err)
+	assert.NotEqual(t, secret, other)
+
+	assert.Len(t, auth.Hash(secret), 64)
+	assert.NotEqual(t, auth.Hash(secret), auth.Hash(other))
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+)
+
+// Identity is the caller named by the verified client certificate of a
+// mutual TLS connection.
+type Identity struct {
+	// Name is the first URI SAN of the certificate, e.g. a SPIFFE ID, or
+	// else its first DNS SAN, or else its common name.
+	Name       string
+	CommonName string
+	DNSNames   []string
+	URIs       []string
+}
+
+// identityContextKey is the context key of the Identity of the caller.
+type identityContextKey struct{}
+
+// IdentityFromCertificate returns the Identity named by cert.
+func IdentityFromCertificate(cert *x509.Certificate) Identity {
+	identity := Identity{
+		Name:       cert.Subject.CommonName,
+		CommonName: cert.Subject.CommonName,
+		DNSNames:   cert.DNSNames,
+	}
+
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+	}
+
+	switch {
+	case len(identity.URIs) > 0:
+		identity.Name = identity.URIs[0]
+	case len(identity.DNSNames) > 0:
+		identity.Name = identity.DNSNames[0]
+	}
+
+	return identity
+}
+
+// NewIdentityContext returns a copy of ctx holding the Identity of the caller.
+func NewIdentityContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext returns the Identity of the caller, and false if it did
+// not present a client certificate.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityContextKey{}).(Identity)
+
+	return identity, ok
+}
@@ -0,0 +1,57 @@
+package auth_test
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"storage/internal/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityFromCertificate(t *testing.T) {
+	t.Parallel()
+
+	spiffeID, err := url.Parse("spiffe://example.org/billing")
+	assert.Nil(t, err)
+
+	for _, tt := range []struct {
+		in      *x509.Certificate
+		name    string
+		outName string
+	}{
+		{
+			name:    "CommonName",
+			in:      &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}},
+			outName: "billing",
+		},
+		{
+			name: "DNSName",
+			in: &x509.Certificate{
+				Subject:  pkix.Name{CommonName: "billing"},
+				DNSNames: []string{"billing.internal", "billing"},
+			},
+			outName: "billing.internal",
+		},
+		{
+			name: "URI",
+			in: &x509.Certificate{
+				Subject:  pkix.Name{CommonName: "billing"},
+				DNSNames: []string{"billing.internal"},
+				URIs:     []*url.URL{spiffeID},
+			},
+			outName: "spiffe://example.org/billing",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			identity := auth.IdentityFromCertificate(tt.in)
+			assert.Equal(t, tt.outName, identity.Name)
+			assert.Equal(t, "billing", identity.CommonName)
+		})
+	}
+}
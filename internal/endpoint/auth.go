@@ -0,0 +1,86 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"storage/internal/auth"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Scopes holds the scope the API key of the callers of every service method
+// needs. The methods missing from it need auth.ScopeAdmin.
+var Scopes = map[string]string{
+	"GetAllUsers":                  auth.ScopeRead,
+	"GetUserByID":                  auth.ScopeRead,
+	"GetIDByUsername":              auth.ScopeRead,
+	"CountLegacyPasswords":         auth.ScopeRead,
+	"InsertUser":                   auth.ScopeWrite,
+	"UpdateUser":                   auth.ScopeWrite,
+	"DeleteUser":                   auth.ScopeWrite,
+	"GetUserByUsernameAndPassword": auth.ScopeVerifyCredentials,
+	"UnlockUser":                   auth.ScopeAdmin,
+}
+
+// AuthenticateMiddleware puts the API key of the caller, as put in the
+// context by the transport, in the context once authenticated. Callers with a
+// missing or unknown API key go on unauthenticated, for AuthorizeMiddleware
+// to reject.
+func AuthenticateMiddleware(authenticator *auth.Authenticator) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request any) (any, error) {
+			key, err := authenticator.Authenticate(ctx, auth.FromContext(ctx))
+
+			switch {
+			case err == nil:
+				ctx = auth.NewAPIKeyContext(ctx, key)
+			case !errors.Is(err, auth.ErrUnauthenticated):
+				return nil, err
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// AuthorizeMiddleware rejects the calls without an API key authenticated by
+// AuthenticateMiddleware with auth.ErrUnauthenticated, or whose API key lacks
+// scope with auth.ErrForbidden, before they reach next.
+func AuthorizeMiddleware(scope string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request any) (any, error) {
+			key, ok := auth.APIKeyFromContext(ctx)
+			if !ok {
+				return nil, auth.ErrUnauthenticated
+			}
+
+			if !auth.HasScope(key, scope) {
+				return nil, fmt.Errorf("%w: %q", auth.ErrForbidden, scope)
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// AuthenticateEndpoints wraps every endpoint in an AuthenticateMiddleware.
+func AuthenticateEndpoints(endpoints Endpoints, authenticator *auth.Authenticator) Endpoints {
+	return wrapMethods(endpoints, func(string) endpoint.Middleware {
+		return AuthenticateMiddleware(authenticator)
+	})
+}
+
+// AuthorizeEndpoints wraps every endpoint in an AuthorizeMiddleware requiring
+// the scope Scopes gives its service method.
+func AuthorizeEndpoints(endpoints Endpoints) Endpoints {
+	return wrapMethods(endpoints, func(method string) endpoint.Middleware {
+		scope, ok := Scopes[method]
+		if !ok {
+			scope = auth.ScopeAdmin
+		}
+
+		return AuthorizeMiddleware(scope)
+	})
+}
@@ -0,0 +1,93 @@
+package endpoint_test
+
+import (
+	"context"
+	"testing"
+
+	"storage/internal/auth"
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/repository"
+
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticateAuthorizeEndpoints(t *testing.T) {
+	t.Parallel()
+
+	keys := repository.NewMemory()
+
+	err := keys.InsertAPIKey(context.TODO(), entity.APIKey{
+		Name:   "writer",
+		Hash:   auth.Hash("sk_writer"),
+		Scopes: []string{auth.ScopeRead, auth.ScopeWrite},
+	})
+	assert.Nil(t, err)
+
+	endpoints := endpoint.AuthenticateEndpoints(
+		endpoint.AuthorizeEndpoints(endpoint.MakeEndpoints(newService(t, mock.NameNoError))),
+		auth.NewAuthenticator(keys),
+	)
+
+	for _, tt := range []struct {
+		inEndpoint kitendpoint.Endpoint
+		inRequest  any
+		outErr     error
+		name       string
+		inKey      string
+	}{
+		{
+			name:       mock.NameNoError,
+			inEndpoint: endpoints.GetUserByID,
+			inRequest:  entity.IDRequest{ID: mock.IDTest},
+			inKey:      "sk_writer",
+		},
+		{
+			name:       "Write",
+			inEndpoint: endpoints.DeleteUser,
+			inRequest:  entity.IDRequest{ID: mock.IDTest},
+			inKey:      "sk_writer",
+		},
+		{
+			name:       "VerifyCredentials",
+			inEndpoint: endpoints.GetUserByUsernameAndPassword,
+			inRequest:  entity.UsernamePasswordRequest{Username: mock.UsernameTest, Password: mock.PasswordTest},
+			inKey:      "sk_writer",
+			outErr:     auth.ErrForbidden,
+		},
+		{
+			name:       "Admin",
+			inEndpoint: endpoints.UnlockUser,
+			inRequest:  entity.IDRequest{ID: mock.IDTest},
+			inKey:      "sk_writer",
+			outErr:     auth.ErrForbidden,
+		},
+		{
+			name:       "NoKey",
+			inEndpoint: endpoints.GetUserByID,
+			inRequest:  entity.IDRequest{ID: mock.IDTest},
+			outErr:     auth.ErrUnauthenticated,
+		},
+		{
+			name:       "UnknownKey",
+			inEndpoint: endpoints.GetUserByID,
+			inRequest:  entity.IDRequest{ID: mock.IDTest},
+			inKey:      "sk_other",
+			outErr:     auth.ErrUnauthenticated,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := tt.inEndpoint(auth.NewContext(context.TODO(), tt.inKey), tt.inRequest)
+			if tt.outErr == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.outErr)
+			}
+		})
+	}
+}
@@ -2,8 +2,6 @@ package endpoint
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
 	"fmt"
 
@@ -17,121 +15,179 @@ var ErrRequest = errors.New("error to request")
 
 // MakeGetAllUsersEndpoint ...
 func MakeGetAllUsersEndpoint(svc service.Service) endpoint.Endpoint {
-	return func(_ context.Context, _ any) (any, error) {
-		var errMessage string
-
-		users, err := svc.GetAllUsers()
-		if err != nil {
-			errMessage = err.Error()
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(entity.ListUsersRequest)
+		if !ok {
+			return nil, fmt.Errorf("%w: isn't of type ListUsersRequest", ErrRequest)
 		}
 
-		return entity.UsersErrorResponse{Users: users, Err: errMessage}, nil
+		users, nextCursor, err := svc.GetAllUsers(ctx, req)
+
+		return entity.UsersErrorResponse{Users: users, NextCursor: nextCursor, Err: err}, nil
 	}
 }
 
 // MakeGetUserByIDEndpoint ...
 func MakeGetUserByIDEndpoint(svc service.Service) endpoint.Endpoint {
-	return func(_ context.Context, request any) (any, error) {
-		var errMessage string
-
+	return func(ctx context.Context, request any) (any, error) {
 		req, ok := request.(entity.IDRequest)
 		if !ok {
 			return nil, fmt.Errorf("%w: isn't of type GenerateTokenRequest", ErrRequest)
 		}
 
-		user, err := svc.GetUserByID(req.ID)
-		if err != nil {
-			errMessage = err.Error()
-		}
+		user, err := svc.GetUserByID(ctx, req.ID)
 
-		return entity.UserErrorResponse{User: user, Err: errMessage}, nil
+		return entity.UserErrorResponse{User: user, Err: err}, nil
 	}
 }
 
 // MakeGetUserByUsernameAndPasswordEndpoint ...
 func MakeGetUserByUsernameAndPasswordEndpoint(svc service.Service) endpoint.Endpoint {
-	return func(_ context.Context, request any) (any, error) {
-		var errMessage string
-
+	return func(ctx context.Context, request any) (any, error) {
 		req, ok := request.(entity.UsernamePasswordRequest)
 		if !ok {
 			return nil, fmt.Errorf("%w: isn't of type GenerateTokenRequest", ErrRequest)
 		}
 
-		passwordHashed := NewHashHex(req.Password)
-
-		user, err := svc.GetUserByUsernameAndPassword(req.Username, passwordHashed)
-		if err != nil {
-			errMessage = err.Error()
-		}
+		user, err := svc.GetUserByUsernameAndPassword(ctx, req.Username, req.Password)
 
-		return entity.UserErrorResponse{User: user, Err: errMessage}, nil
+		return entity.UserErrorResponse{User: user, Err: err}, nil
 	}
 }
 
 // MakeGetIDByUsernameEndpoint ...
 func MakeGetIDByUsernameEndpoint(svc service.Service) endpoint.Endpoint {
-	return func(_ context.Context, request any) (any, error) {
-		var errMessage string
-
+	return func(ctx context.Context, request any) (any, error) {
 		req, ok := request.(entity.UsernameRequest)
 		if !ok {
 			return nil, fmt.Errorf("%w: isn't of type GenerateTokenRequest", ErrRequest)
 		}
 
-		id, err := svc.GetIDByUsername(req.Username)
-		if err != nil {
-			errMessage = err.Error()
-		}
+		id, err := svc.GetIDByUsername(ctx, req.Username)
 
-		return entity.IDErrorResponse{ID: id, Err: errMessage}, nil
+		return entity.IDErrorResponse{ID: id, Err: err}, nil
 	}
 }
 
 // MakeInsertUserEndpoint ...
 func MakeInsertUserEndpoint(svc service.Service) endpoint.Endpoint {
-	return func(_ context.Context, request any) (any, error) {
-		var errMessage string
-
+	return func(ctx context.Context, request any) (any, error) {
 		req, ok := request.(entity.UsernamePasswordEmailRequest)
 		if !ok {
 			return nil, fmt.Errorf("%w: isn't of type GenerateTokenRequest", ErrRequest)
 		}
 
-		passwordHashed := NewHashHex(req.Password)
+		err := svc.InsertUser(ctx, req.Username, req.Password, req.Email)
 
-		err := svc.InsertUser(req.Username, passwordHashed, req.Email)
-		if err != nil {
-			errMessage = err.Error()
+		return entity.ErrorResponse{Err: err}, nil
+	}
+}
+
+// MakeUpdateUserEndpoint ...
+func MakeUpdateUserEndpoint(svc service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(entity.UpdateUserRequest)
+		if !ok {
+			return nil, fmt.Errorf("%w: isn't of type UpdateUserRequest", ErrRequest)
 		}
 
-		return entity.ErrorResponse{Err: errMessage}, nil
+		user, err := svc.UpdateUser(ctx, req.ID, req.Patch)
+
+		return entity.UserErrorResponse{User: user, Err: err}, nil
 	}
 }
 
 // MakeDeleteUserEndpoint ...
 func MakeDeleteUserEndpoint(svc service.Service) endpoint.Endpoint {
-	return func(_ context.Context, request any) (any, error) {
-		var errMessage string
-
+	return func(ctx context.Context, request any) (any, error) {
 		req, ok := request.(entity.IDRequest)
 		if !ok {
 			return nil, fmt.Errorf("%w: isn't of type GenerateTokenRequest", ErrRequest)
 		}
 
-		rowsAffected, err := svc.DeleteUser(req.ID)
-		if err != nil {
-			errMessage = err.Error()
+		rowsAffected, err := svc.DeleteUser(ctx, req.ID)
+
+		return entity.RowsErrorResponse{RowsAffected: rowsAffected, Err: err}, nil
+	}
+}
+
+// MakeCountLegacyPasswordsEndpoint ...
+func MakeCountLegacyPasswordsEndpoint(svc service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, _ any) (any, error) {
+		count, err := svc.CountLegacyPasswords(ctx)
+
+		return entity.CountErrorResponse{Count: count, Err: err}, nil
+	}
+}
+
+// MakeUnlockUserEndpoint ...
+func MakeUnlockUserEndpoint(svc service.Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(entity.IDRequest)
+		if !ok {
+			return nil, fmt.Errorf("%w: isn't of type IDRequest", ErrRequest)
 		}
 
-		return entity.RowsErrorResponse{RowsAffected: rowsAffected, Err: errMessage}, nil
+		err := svc.UnlockUser(ctx, req.ID)
+
+		return entity.ErrorResponse{Err: err}, nil
 	}
 }
 
-func NewHashHex(data string) (hash string) {
-	hasher := sha256.New()
+// Endpoints collects every endpoint of the service so that transports can
+// mount them without knowing how they are built.
+type Endpoints struct {
+	GetAllUsers                  endpoint.Endpoint
+	GetUserByID                  endpoint.Endpoint
+	GetUserByUsernameAndPassword endpoint.Endpoint
+	GetIDByUsername              endpoint.Endpoint
+	InsertUser                   endpoint.Endpoint
+	UpdateUser                   endpoint.Endpoint
+	DeleteUser                   endpoint.Endpoint
+	CountLegacyPasswords         endpoint.Endpoint
+	UnlockUser                   endpoint.Endpoint
+}
 
-	hasher.Write([]byte(data))
+// MakeEndpoints builds every endpoint of svc, wrapped in middlewares. The
+// first middleware is the outermost one.
+func MakeEndpoints(svc service.Service, middlewares ...endpoint.Middleware) Endpoints {
+	wrap := func(e endpoint.Endpoint) endpoint.Endpoint {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			e = middlewares[i](e)
+		}
+
+		return e
+	}
 
-	return hex.EncodeToString(hasher.Sum(nil))
+	return Endpoints{
+		GetAllUsers:                  wrap(MakeGetAllUsersEndpoint(svc)),
+		GetUserByID:                  wrap(MakeGetUserByIDEndpoint(svc)),
+		GetUserByUsernameAndPassword: wrap(MakeGetUserByUsernameAndPasswordEndpoint(svc)),
+		GetIDByUsername:              wrap(MakeGetIDByUsernameEndpoint(svc)),
+		InsertUser:                   wrap(MakeInsertUserEndpoint(svc)),
+		UpdateUser:                   wrap(MakeUpdateUserEndpoint(svc)),
+		DeleteUser:                   wrap(MakeDeleteUserEndpoint(svc)),
+		CountLegacyPasswords:         wrap(MakeCountLegacyPasswordsEndpoint(svc)),
+		UnlockUser:                   wrap(MakeUnlockUserEndpoint(svc)),
+	}
+}
+
+// wrapMethods wraps every endpoint of endpoints in the middleware that
+// middleware returns for the name of its service method.
+func wrapMethods(endpoints Endpoints, middleware func(method string) endpoint.Middleware) Endpoints {
+	wrap := func(method string, e endpoint.Endpoint) endpoint.Endpoint {
+		return middleware(method)(e)
+	}
+
+	return Endpoints{
+		GetAllUsers:                  wrap("GetAllUsers", endpoints.GetAllUsers),
+		GetUserByID:                  wrap("GetUserByID", endpoints.GetUserByID),
+		GetUserByUsernameAndPassword: wrap("GetUserByUsernameAndPassword", endpoints.GetUserByUsernameAndPassword),
+		GetIDByUsername:              wrap("GetIDByUsername", endpoints.GetIDByUsername),
+		InsertUser:                   wrap("InsertUser", endpoints.InsertUser),
+		UpdateUser:                   wrap("UpdateUser", endpoints.UpdateUser),
+		DeleteUser:                   wrap("DeleteUser", endpoints.DeleteUser),
+		CountLegacyPasswords:         wrap("CountLegacyPasswords", endpoints.CountLegacyPasswords),
+		UnlockUser:                   wrap("UnlockUser", endpoints.UnlockUser),
+	}
 }
@@ -7,86 +7,98 @@ import (
 	"storage/internal/endpoint"
 	"storage/internal/entity"
 	"storage/internal/entity/mock"
+	"storage/internal/password"
+	"storage/internal/repository"
 	"storage/internal/service"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type incorrectRequest struct {
 	incorrect bool
 }
 
+// newService returns a service over an in-memory repository holding the mock
+// user, or over a failing one for the mock.NameErrorDBClosed cases.
+func newService(t *testing.T, name string) service.Service {
+	t.Helper()
+
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+
+	if name == mock.NameErrorDBClosed {
+		return service.GetService(mock.FailingRepository{}, hasher)
+	}
+
+	passwordHashed, err := hasher.Hash(mock.PasswordTest)
+	assert.Nil(t, err)
+
+	repo := repository.NewMemory()
+
+	err = repo.InsertUser(context.TODO(), entity.User{
+		Username: mock.UsernameTest,
+		Password: passwordHashed,
+		Email:    mock.EmailTest,
+	})
+	assert.Nil(t, err)
+
+	return service.GetService(repo, hasher)
+}
+
 func TestMakeGetAllUsersEndpoint(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		inRequest                          any
-		name                               string
-		outUsername, outPassword, outEmail string
-		outErr                             string
-		outID                              int
+		inRequest any
+		name      string
+		outErr    string
 	}{
 		{
-			name:        mock.NameNoError,
-			outID:       mock.IDTest,
-			outUsername: mock.UsernameTest,
-			outEmail:    mock.EmailTest,
-			inRequest:   entity.EmptyRequest{},
-			outErr:      "",
+			name:      mock.NameNoError,
+			inRequest: entity.ListUsersRequest{},
+			outErr:    "",
+		},
+		{
+			name: mock.NameErrorRequest,
+			inRequest: incorrectRequest{
+				incorrect: true,
+			},
+			outErr: "isn't of type",
 		},
 		{
-			name:        mock.NameErrorDBClosed,
-			outID:       mock.IDTest,
-			outUsername: mock.UsernameTest,
-			outEmail:    mock.EmailTest,
-			inRequest:   entity.EmptyRequest{},
-			outErr:      mock.ErrDatabaseClosed,
+			name:      mock.NameErrorDBClosed,
+			inRequest: entity.ListUsersRequest{},
+			outErr:    mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
-
-			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
-			}
-
-			svc := service.GetService(db)
-
-			rows := sqlmock.NewRows(
-				[]string{
-					"id",
-					"username",
-					"email",
-				}).AddRow(
-				tt.outID,
-				tt.outUsername,
-				tt.outEmail,
-			)
+			var resultErr string
 
-			dbMock.ExpectQuery("^SELECT id, username, email FROM users").WillReturnRows(rows)
+			svc := newService(t, tt.name)
 
 			r, err := endpoint.MakeGetAllUsersEndpoint(svc)(context.TODO(), tt.inRequest)
 			if err != nil {
-				assert.Error(t, err)
+				resultErr = err.Error()
 			}
 
 			result, ok := r.(entity.UsersErrorResponse)
 			if !ok {
-				assert.Fail(t, "response is not of the type indicated")
+				if tt.name != mock.NameErrorRequest {
+					assert.Fail(t, "response is not of the type indicated")
+				}
+			}
+
+			if result.Err != nil {
+				resultErr = result.Err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
-				assert.Empty(t, result.Err)
+				assert.Nil(t, result.Err)
 			} else {
-				assert.Contains(t, result.Err, tt.outErr)
+				assert.Contains(t, resultErr, tt.outErr)
 			}
 		})
 	}
@@ -96,20 +108,14 @@ func TestMakeGetUserByIDEndpoint(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		inRequest                       any
-		name                            string
-		inUsername, inPassword, inEmail string
-		outErr                          string
-		inID                            int
+		inRequest any
+		name      string
+		outErr    string
 	}{
 		{
-			name:       mock.NameNoError,
-			inID:       mock.IDTest,
-			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
-			inRequest:  entity.IDRequest{ID: mock.IDTest},
-			outErr:     "",
+			name:      mock.NameNoError,
+			inRequest: entity.IDRequest{ID: mock.IDTest},
+			outErr:    "",
 		},
 		{
 			name: mock.NameErrorRequest,
@@ -119,13 +125,9 @@ func TestMakeGetUserByIDEndpoint(t *testing.T) {
 			outErr: "isn't of type",
 		},
 		{
-			name:       mock.NameErrorDBClosed,
-			inID:       mock.IDTest,
-			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
-			inRequest:  entity.IDRequest{},
-			outErr:     mock.ErrDatabaseClosed,
+			name:      mock.NameErrorDBClosed,
+			inRequest: entity.IDRequest{},
+			outErr:    mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
@@ -134,33 +136,7 @@ func TestMakeGetUserByIDEndpoint(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
-
-			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
-			}
-
-			svc := service.GetService(db)
-
-			rows := sqlmock.NewRows(
-				[]string{
-					"id",
-					"username",
-					"password",
-					"email",
-				}).AddRow(
-				tt.inID,
-				tt.inUsername,
-				tt.inPassword,
-				tt.inEmail,
-			)
-
-			dbMock.ExpectQuery("^SELECT id, username, password, email FROM users").
-				WithArgs(tt.inID).WillReturnRows(rows)
+			svc := newService(t, tt.name)
 
 			r, err := endpoint.MakeGetUserByIDEndpoint(svc)(context.TODO(), tt.inRequest)
 			if err != nil {
@@ -174,12 +150,12 @@ func TestMakeGetUserByIDEndpoint(t *testing.T) {
 				}
 			}
 
-			if result.Err != "" {
-				resultErr = result.Err
+			if result.Err != nil {
+				resultErr = result.Err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
-				assert.Empty(t, result.Err)
+				assert.Nil(t, result.Err)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -191,18 +167,12 @@ func TestMakeGetUserByUsernameAndPasswordEndpoint(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		inRequest                       any
-		name                            string
-		inUsername, inPassword, inEmail string
-		outErr                          string
-		inID                            int
+		inRequest any
+		name      string
+		outErr    string
 	}{
 		{
-			name:       mock.NameNoError,
-			inID:       mock.IDTest,
-			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
+			name: mock.NameNoError,
 			inRequest: entity.UsernamePasswordRequest{
 				Username: mock.UsernameTest,
 				Password: mock.PasswordTest,
@@ -217,13 +187,9 @@ func TestMakeGetUserByUsernameAndPasswordEndpoint(t *testing.T) {
 			outErr: "isn't of type",
 		},
 		{
-			name:       mock.NameErrorDBClosed,
-			inID:       mock.IDTest,
-			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
-			inRequest:  entity.UsernamePasswordRequest{},
-			outErr:     mock.ErrDatabaseClosed,
+			name:      mock.NameErrorDBClosed,
+			inRequest: entity.UsernamePasswordRequest{},
+			outErr:    mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
@@ -232,35 +198,7 @@ func TestMakeGetUserByUsernameAndPasswordEndpoint(t *testing.T) {
 
 			var resultErr string
 
-			passwordHashed := endpoint.NewHashHex(tt.inPassword)
-
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
-
-			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
-			}
-
-			svc := service.GetService(db)
-
-			rows := sqlmock.NewRows(
-				[]string{
-					"id",
-					"username",
-					"password",
-					"email",
-				}).AddRow(
-				tt.inID,
-				tt.inUsername,
-				passwordHashed,
-				tt.inEmail,
-			)
-
-			dbMock.ExpectQuery("^SELECT id, username, password, email FROM users").
-				WithArgs(tt.inUsername, passwordHashed).WillReturnRows(rows)
+			svc := newService(t, tt.name)
 
 			r, err := endpoint.MakeGetUserByUsernameAndPasswordEndpoint(svc)(
 				context.TODO(),
@@ -277,12 +215,12 @@ func TestMakeGetUserByUsernameAndPasswordEndpoint(t *testing.T) {
 				}
 			}
 
-			if result.Err != "" {
-				resultErr = result.Err
+			if result.Err != nil {
+				resultErr = result.Err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
-				assert.Empty(t, result.Err)
+				assert.Nil(t, result.Err)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -294,16 +232,12 @@ func TestGetIDByUsernameEndpoint(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		inRequest  any
-		name       string
-		inUsername string
-		outErr     string
-		inID       int
+		inRequest any
+		name      string
+		outErr    string
 	}{
 		{
-			name:       mock.NameNoError,
-			inID:       mock.IDTest,
-			inUsername: mock.UsernameTest,
+			name: mock.NameNoError,
 			inRequest: entity.UsernameRequest{
 				Username: mock.UsernameTest,
 			},
@@ -317,11 +251,9 @@ func TestGetIDByUsernameEndpoint(t *testing.T) {
 			outErr: "isn't of type",
 		},
 		{
-			name:       mock.NameErrorDBClosed,
-			inID:       mock.IDTest,
-			inUsername: mock.UsernameTest,
-			inRequest:  entity.UsernameRequest{},
-			outErr:     mock.ErrDatabaseClosed,
+			name:      mock.NameErrorDBClosed,
+			inRequest: entity.UsernameRequest{},
+			outErr:    mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
@@ -330,21 +262,7 @@ func TestGetIDByUsernameEndpoint(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
-
-			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
-			}
-
-			svc := service.GetService(db)
-
-			rows := sqlmock.NewRows([]string{"id"}).AddRow(tt.inID)
-
-			dbMock.ExpectQuery("^SELECT id FROM users").WithArgs(tt.inUsername).WillReturnRows(rows)
+			svc := newService(t, tt.name)
 
 			r, err := endpoint.MakeGetIDByUsernameEndpoint(svc)(context.TODO(), tt.inRequest)
 			if err != nil {
@@ -358,12 +276,12 @@ func TestGetIDByUsernameEndpoint(t *testing.T) {
 				}
 			}
 
-			if result.Err != "" {
-				resultErr = result.Err
+			if result.Err != nil {
+				resultErr = result.Err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
-				assert.Empty(t, result.Err)
+				assert.Nil(t, result.Err)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -375,20 +293,16 @@ func TestMakeInsertUserEndpoint(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		inRequest                       any
-		name                            string
-		inUsername, inPassword, inEmail string
-		outErr                          string
+		inRequest any
+		name      string
+		outErr    string
 	}{
 		{
-			name:       mock.NameNoError,
-			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
+			name: mock.NameNoError,
 			inRequest: entity.UsernamePasswordEmailRequest{
-				Username: mock.UsernameTest,
+				Username: "other",
 				Password: mock.PasswordTest,
-				Email:    mock.EmailTest,
+				Email:    "other@other.com",
 			},
 			outErr: "",
 		},
@@ -400,12 +314,9 @@ func TestMakeInsertUserEndpoint(t *testing.T) {
 			outErr: "isn't of type",
 		},
 		{
-			name:       mock.NameErrorDBClosed,
-			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
-			inRequest:  entity.UsernamePasswordEmailRequest{},
-			outErr:     mock.ErrDatabaseClosed,
+			name:      mock.NameErrorDBClosed,
+			inRequest: entity.UsernamePasswordEmailRequest{},
+			outErr:    mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
@@ -414,26 +325,7 @@ func TestMakeInsertUserEndpoint(t *testing.T) {
 
 			var resultErr string
 
-			passwordHashed := endpoint.NewHashHex(tt.inPassword)
-
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
-
-			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
-			}
-
-			svc := service.GetService(db)
-
-			dbMock.ExpectExec("^INSERT INTO users").
-				WithArgs(
-					tt.inUsername,
-					passwordHashed,
-					tt.inEmail,
-				).WillReturnResult(sqlmock.NewResult(0, 1))
+			svc := newService(t, tt.name)
 
 			r, err := endpoint.MakeInsertUserEndpoint(svc)(context.TODO(), tt.inRequest)
 			if err != nil {
@@ -447,12 +339,12 @@ func TestMakeInsertUserEndpoint(t *testing.T) {
 				}
 			}
 
-			if result.Err != "" {
-				resultErr = result.Err
+			if result.Err != nil {
+				resultErr = result.Err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
-				assert.Empty(t, result.Err)
+				assert.Nil(t, result.Err)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -467,11 +359,9 @@ func TestMakeDeleteUserEndpoint(t *testing.T) {
 		inRequest any
 		name      string
 		outErr    string
-		inID      int
 	}{
 		{
 			name: mock.NameNoError,
-			inID: mock.IDTest,
 			inRequest: entity.IDRequest{
 				ID: mock.IDTest,
 			},
@@ -486,7 +376,6 @@ func TestMakeDeleteUserEndpoint(t *testing.T) {
 		},
 		{
 			name:      mock.NameErrorDBClosed,
-			inID:      mock.IDTest,
 			inRequest: entity.IDRequest{},
 			outErr:    mock.ErrDatabaseClosed,
 		},
@@ -497,39 +386,196 @@ func TestMakeDeleteUserEndpoint(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
+			svc := newService(t, tt.name)
+
+			r, err := endpoint.MakeDeleteUserEndpoint(svc)(context.TODO(), tt.inRequest)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			result, ok := r.(entity.RowsErrorResponse)
+			if !ok {
+				if tt.name != mock.NameErrorRequest {
+					assert.Fail(t, "response is not of the type indicated")
+				}
+			}
+
+			if result.Err != nil {
+				resultErr = result.Err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Nil(t, result.Err)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestMakeUnlockUserEndpoint(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		inRequest any
+		name      string
+		outErr    string
+	}{
+		{
+			name:      mock.NameNoError,
+			inRequest: entity.IDRequest{ID: mock.IDTest},
+			outErr:    "",
+		},
+		{
+			name:      mock.NameErrorRequest,
+			inRequest: incorrectRequest{incorrect: true},
+			outErr:    "isn't of type",
+		},
+		{
+			name:      mock.NameErrorNoRows,
+			inRequest: entity.IDRequest{ID: mock.IDTest + 9},
+			outErr:    service.ErrUserNotFound.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			svc := newService(t, tt.name)
+
+			r, err := endpoint.MakeUnlockUserEndpoint(svc)(context.TODO(), tt.inRequest)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			result, ok := r.(entity.ErrorResponse)
+			if !ok && tt.name != mock.NameErrorRequest {
+				assert.Fail(t, "response is not of the type indicated")
+			}
+
+			if result.Err != nil {
+				resultErr = result.Err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestMakeCountLegacyPasswordsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		inRequest any
+		name      string
+		outErr    string
+		outCount  int
+	}{
+		{
+			name:      mock.NameNoError,
+			inRequest: entity.EmptyRequest{},
+			outCount:  0,
+			outErr:    "",
+		},
+		{
+			name:      mock.NameErrorDBClosed,
+			inRequest: entity.EmptyRequest{},
+			outErr:    mock.ErrDatabaseClosed,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := newService(t, tt.name)
+
+			r, err := endpoint.MakeCountLegacyPasswordsEndpoint(svc)(context.TODO(), tt.inRequest)
 			if err != nil {
 				assert.Error(t, err)
 			}
-			defer db.Close()
 
-			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
+			result, ok := r.(entity.CountErrorResponse)
+			if !ok {
+				assert.Fail(t, "response is not of the type indicated")
 			}
 
-			svc := service.GetService(db)
+			if tt.name == mock.NameNoError {
+				assert.Nil(t, result.Err)
+				assert.Equal(t, tt.outCount, result.Count)
+			} else {
+				assert.ErrorContains(t, result.Err, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestMakeUpdateUserEndpoint(t *testing.T) {
+	t.Parallel()
 
-			dbMock.ExpectExec("^DELETE FROM users").
-				WithArgs(tt.inID).WillReturnResult(sqlmock.NewResult(0, 1))
+	email := mock.EmailTest
 
-			r, err := endpoint.MakeDeleteUserEndpoint(svc)(context.TODO(), tt.inRequest)
+	for _, tt := range []struct {
+		inRequest any
+		name      string
+		outErr    string
+	}{
+		{
+			name: mock.NameNoError,
+			inRequest: entity.UpdateUserRequest{
+				ID:    mock.IDTest,
+				Patch: entity.UserPatch{Email: &email},
+			},
+			outErr: "",
+		},
+		{
+			name: mock.NameErrorRequest,
+			inRequest: incorrectRequest{
+				incorrect: true,
+			},
+			outErr: "isn't of type",
+		},
+		{
+			name: mock.NameErrorDBClosed,
+			inRequest: entity.UpdateUserRequest{
+				ID:    mock.IDTest,
+				Patch: entity.UserPatch{Email: &email},
+			},
+			outErr: mock.ErrDatabaseClosed,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			svc := newService(t, tt.name)
+
+			r, err := endpoint.MakeUpdateUserEndpoint(svc)(context.TODO(), tt.inRequest)
 			if err != nil {
 				resultErr = err.Error()
 			}
 
-			result, ok := r.(entity.RowsErrorResponse)
+			result, ok := r.(entity.UserErrorResponse)
 			if !ok {
 				if tt.name != mock.NameErrorRequest {
 					assert.Fail(t, "response is not of the type indicated")
 				}
 			}
 
-			if result.Err != "" {
-				resultErr = result.Err
+			if result.Err != nil {
+				resultErr = result.Err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
-				assert.Empty(t, result.Err)
+				assert.Nil(t, result.Err)
+				assert.Equal(t, mock.EmailTest, result.User.Email)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -0,0 +1,54 @@
+package endpoint
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+)
+
+// Metrics are the instruments of InstrumentingMiddleware. Requests and
+// Latency are labeled with "method" and "outcome", Errors with "method".
+type Metrics struct {
+	Requests metrics.Counter
+	Errors   metrics.Counter
+	Latency  metrics.Histogram
+}
+
+// Outcomes of a call, as labeled by InstrumentingMiddleware.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// InstrumentingMiddleware counts every call to method, and those that fail,
+// and observes how long it took in seconds. A call fails when it returns an
+// error or a response whose Failed error is not nil.
+func InstrumentingMiddleware(m Metrics, method string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request any) (response any, err error) {
+			defer func(begin time.Time) {
+				outcome := OutcomeSuccess
+				if failer, ok := response.(endpoint.Failer); err != nil || (ok && failer.Failed() != nil) {
+					outcome = OutcomeError
+
+					m.Errors.With("method", method).Add(1)
+				}
+
+				m.Requests.With("method", method, "outcome", outcome).Add(1)
+				m.Latency.With("method", method, "outcome", outcome).Observe(time.Since(begin).Seconds())
+			}(time.Now())
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// InstrumentEndpoints wraps every endpoint of endpoints in an
+// InstrumentingMiddleware labeled with the name of its service method.
+func InstrumentEndpoints(endpoints Endpoints, m Metrics) Endpoints {
+	return wrapMethods(endpoints, func(method string) endpoint.Middleware {
+		return InstrumentingMiddleware(m, method)
+	})
+}
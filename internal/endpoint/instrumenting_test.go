@@ -0,0 +1,75 @@
+package endpoint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentingMiddleware(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		outResponse any
+		outErr      error
+		name        string
+		outOutcome  string
+		outErrors   float64
+	}{
+		{
+			name:        mock.NameNoError,
+			outResponse: entity.IDErrorResponse{ID: mock.IDTest},
+			outOutcome:  endpoint.OutcomeSuccess,
+		},
+		{
+			name:        "ErrorResponse",
+			outResponse: entity.IDErrorResponse{Err: errors.New(mock.ErrDatabaseClosed)},
+			outOutcome:  endpoint.OutcomeError,
+			outErrors:   1,
+		},
+		{
+			name:       "ErrorEndpoint",
+			outErr:     endpoint.ErrRequest,
+			outOutcome: endpoint.OutcomeError,
+			outErrors:  1,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			requests := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests"}, []string{"method", "outcome"})
+			errs := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "errors"}, []string{"method"})
+			latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "latency"}, []string{"method", "outcome"})
+
+			m := endpoint.Metrics{
+				Requests: kitprometheus.NewCounter(requests),
+				Errors:   kitprometheus.NewCounter(errs),
+				Latency:  kitprometheus.NewHistogram(latency),
+			}
+
+			next := func(context.Context, any) (any, error) {
+				return tt.outResponse, tt.outErr
+			}
+
+			endpoints := endpoint.InstrumentEndpoints(endpoint.Endpoints{GetIDByUsername: next}, m)
+
+			response, err := endpoints.GetIDByUsername(context.TODO(), entity.UsernameRequest{})
+
+			assert.Equal(t, tt.outResponse, response)
+			assert.ErrorIs(t, err, tt.outErr)
+			assert.Equal(t, float64(1), testutil.ToFloat64(requests.WithLabelValues("GetIDByUsername", tt.outOutcome)))
+			assert.Equal(t, tt.outErrors, testutil.ToFloat64(errs.WithLabelValues("GetIDByUsername")))
+			assert.Equal(t, 1, testutil.CollectAndCount(latency))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package endpoint
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"storage/internal/requestid"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/log"
+)
+
+// loggedJSON is a JSON document that JSON loggers embed as is and logfmt
+// loggers write as a string.
+type loggedJSON []byte
+
+// redacted replaces the logged value of every password and hash.
+const redacted = "[REDACTED]"
+
+// LoggingMiddleware logs every call of the service method named method with
+// its request and response, the error it failed with, if any, and how long it
+// took. Passwords and hashes are redacted from the logged request and
+// response, usernames are kept.
+func LoggingMiddleware(logger log.Logger, method string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request any) (response any, err error) {
+			defer func(begin time.Time) {
+				failure := err
+				if failer, ok := response.(endpoint.Failer); ok && failure == nil {
+					failure = failer.Failed()
+				}
+
+				_ = logger.Log(
+					"method", method,
+					"request_id", requestid.FromContext(ctx),
+					"request", redact(request),
+					"response", redact(response),
+					"err", failure,
+					"took", time.Since(begin),
+				)
+			}(time.Now())
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// LogEndpoints wraps every endpoint of endpoints in a LoggingMiddleware
+// logging the name of its service method.
+func LogEndpoints(endpoints Endpoints, logger log.Logger) Endpoints {
+	return wrapMethods(endpoints, func(method string) endpoint.Middleware {
+		return LoggingMiddleware(logger, method)
+	})
+}
+
+// redact returns v as JSON with the value of every field whose name holds
+// "password" or "hash", in any case, replaced by redacted.
+func redact(v any) loggedJSON {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return loggedJSON(`"unloggable"`)
+	}
+
+	var tree any
+
+	if err = json.Unmarshal(data, &tree); err != nil {
+		return loggedJSON(`"unloggable"`)
+	}
+
+	data, err = json.Marshal(redactTree(tree))
+	if err != nil {
+		return loggedJSON(`"unloggable"`)
+	}
+
+	return data
+}
+
+// MarshalJSON ...
+func (j loggedJSON) MarshalJSON() ([]byte, error) {
+	return j, nil
+}
+
+// String ...
+func (j loggedJSON) String() string {
+	return string(j)
+}
+
+func redactTree(tree any) any {
+	switch node := tree.(type) {
+	case map[string]any:
+		for key, value := range node {
+			lower := strings.ToLower(key)
+			if strings.Contains(lower, "password") || strings.Contains(lower, "hash") {
+				node[key] = redacted
+			} else {
+				node[key] = redactTree(value)
+			}
+		}
+	case []any:
+		for i, value := range node {
+			node[i] = redactTree(value)
+		}
+	}
+
+	return tree
+}
@@ -0,0 +1,100 @@
+package endpoint_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/requestid"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		inRequest   any
+		outResponse any
+		outErr      error
+		name        string
+		outLog      []string
+	}{
+		{
+			name: mock.NameNoError,
+			inRequest: entity.UsernamePasswordRequest{
+				Username: mock.UsernameTest,
+				Password: mock.PasswordTest,
+			},
+			outResponse: entity.UserErrorResponse{User: entity.User{
+				ID:       mock.IDTest,
+				Username: mock.UsernameTest,
+				Password: mock.LegacyHashTest,
+				Email:    mock.EmailTest,
+			}},
+			outLog: []string{
+				"method=GetUserByID",
+				"request_id=abc",
+				`\"username\":\"username\"`,
+				`\"password\":\"[REDACTED]\"`,
+				"err=null",
+			},
+		},
+		{
+			name:        "ErrorResponse",
+			inRequest:   entity.IDRequest{ID: mock.IDTest},
+			outResponse: entity.UserErrorResponse{Err: errors.New(mock.ErrDatabaseClosed)},
+			outLog:      []string{`request="{\"id\":1}"`, `err="` + mock.ErrDatabaseClosed + `"`},
+		},
+		{
+			name:      "ErrorEndpoint",
+			inRequest: entity.EmptyRequest{},
+			outErr:    endpoint.ErrRequest,
+			outLog:    []string{`response="null"`, `err="` + endpoint.ErrRequest.Error() + `"`},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			next := func(context.Context, any) (any, error) {
+				return tt.outResponse, tt.outErr
+			}
+
+			ctx := requestid.NewContext(context.TODO(), "abc")
+
+			_, err := endpoint.LoggingMiddleware(log.NewLogfmtLogger(&buf), "GetUserByID")(next)(ctx, tt.inRequest)
+			assert.Equal(t, tt.outErr, err)
+
+			for _, line := range tt.outLog {
+				assert.Contains(t, buf.String(), line)
+			}
+
+			assert.NotContains(t, buf.String(), `\"password\":\"`+mock.PasswordTest)
+			assert.NotContains(t, buf.String(), mock.LegacyHashTest)
+		})
+	}
+}
+
+func TestLogEndpoints(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	endpoints := endpoint.LogEndpoints(
+		endpoint.MakeEndpoints(newService(t, mock.NameNoError)),
+		log.NewLogfmtLogger(&buf),
+	)
+
+	_, err := endpoints.DeleteUser(context.TODO(), entity.IDRequest{ID: mock.IDTest})
+	assert.Nil(t, err)
+
+	assert.Contains(t, buf.String(), "method=DeleteUser")
+}
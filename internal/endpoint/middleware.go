@@ -0,0 +1,27 @@
+package endpoint
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// TimeoutMiddleware gives every request a deadline of timeout, so the
+// service stops waiting on the database once it is exceeded. An earlier
+// deadline already present in the context is kept. A non-positive timeout
+// disables the middleware.
+func TimeoutMiddleware(timeout time.Duration) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		if timeout <= 0 {
+			return next
+		}
+
+		return func(ctx context.Context, request any) (any, error) {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return next(ctx, request)
+		}
+	}
+}
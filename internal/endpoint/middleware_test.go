@@ -0,0 +1,65 @@
+package endpoint_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity/mock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutMiddleware(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name        string
+		inTimeout   time.Duration
+		outDeadline bool
+	}{
+		{
+			name:        mock.NameNoError,
+			inTimeout:   time.Second,
+			outDeadline: true,
+		},
+		{
+			name:        "Disabled",
+			inTimeout:   0,
+			outDeadline: false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var hasDeadline bool
+
+			next := func(ctx context.Context, _ any) (any, error) {
+				_, hasDeadline = ctx.Deadline()
+
+				return nil, ctx.Err()
+			}
+
+			_, err := endpoint.TimeoutMiddleware(tt.inTimeout)(next)(context.TODO(), nil)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.outDeadline, hasDeadline)
+		})
+	}
+}
+
+func TestTimeoutMiddlewareExceeded(t *testing.T) {
+	t.Parallel()
+
+	next := func(ctx context.Context, _ any) (any, error) {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	_, err := endpoint.TimeoutMiddleware(time.Millisecond)(next)(context.TODO(), nil)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
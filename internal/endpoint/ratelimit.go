@@ -0,0 +1,71 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"storage/internal/auth"
+	"storage/internal/ratelimit"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+var ErrUnknownMethod = errors.New("unknown method")
+
+// RateLimitMiddleware rejects the calls of a client over the limit of
+// limiter with a *ratelimit.Error, before they reach next. A client is keyed
+// by the API key AuthenticateMiddleware authenticated it by, if any, or else
+// as keyed by the transport in the context, so that unknown API keys cannot
+// get a bucket of their own.
+func RateLimitMiddleware(limiter *ratelimit.Limiter) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request any) (any, error) {
+			key := ratelimit.FromContext(ctx)
+			if apiKey, ok := auth.APIKeyFromContext(ctx); ok {
+				key = ratelimit.APIKeyClientKey(apiKey.Name)
+			}
+
+			if ok, retryAfter := limiter.Allow(key); !ok {
+				return nil, &ratelimit.Error{RetryAfter: retryAfter}
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// RateLimitEndpoints wraps every endpoint whose service method names a
+// limiter of limiters in a RateLimitMiddleware, leaving the others as they
+// are. It fails with ErrUnknownMethod for the limiters of no method.
+func RateLimitEndpoints(endpoints Endpoints, limiters map[string]*ratelimit.Limiter) (Endpoints, error) {
+	unknown := make(map[string]bool, len(limiters))
+	for method := range limiters {
+		unknown[method] = true
+	}
+
+	endpoints = wrapMethods(endpoints, func(method string) endpoint.Middleware {
+		delete(unknown, method)
+
+		limiter, ok := limiters[method]
+		if !ok {
+			return func(next endpoint.Endpoint) endpoint.Endpoint { return next }
+		}
+
+		return RateLimitMiddleware(limiter)
+	})
+
+	if len(unknown) > 0 {
+		methods := make([]string, 0, len(unknown))
+		for method := range unknown {
+			methods = append(methods, method)
+		}
+
+		sort.Strings(methods)
+
+		return Endpoints{}, fmt.Errorf("%w: %v", ErrUnknownMethod, methods)
+	}
+
+	return endpoints, nil
+}
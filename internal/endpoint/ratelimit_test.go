@@ -0,0 +1,94 @@
+package endpoint_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"storage/internal/auth"
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/ratelimit"
+
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitEndpoints(t *testing.T) {
+	t.Parallel()
+
+	endpoints, err := endpoint.RateLimitEndpoints(
+		endpoint.MakeEndpoints(newService(t, mock.NameNoError)),
+		map[string]*ratelimit.Limiter{
+			"GetUserByID": ratelimit.New(ratelimit.Limit{Rate: rate.Every(time.Hour), Burst: 1}),
+		},
+	)
+	assert.Nil(t, err)
+
+	ctx := ratelimit.NewContext(context.TODO(), "ip:192.0.2.1")
+	request := entity.IDRequest{ID: mock.IDTest}
+
+	_, err = endpoints.GetUserByID(ctx, request)
+	assert.Nil(t, err)
+
+	_, err = endpoints.GetUserByID(ctx, request)
+	assert.ErrorIs(t, err, kitratelimit.ErrLimited)
+
+	var limitErr *ratelimit.Error
+
+	if assert.ErrorAs(t, err, &limitErr) {
+		assert.Greater(t, limitErr.RetryAfter, time.Duration(0))
+	}
+
+	// Another client, or another method, is not limited.
+	_, err = endpoints.GetUserByID(ratelimit.NewContext(context.TODO(), "ip:192.0.2.2"), request)
+	assert.Nil(t, err)
+
+	_, err = endpoints.DeleteUser(ctx, request)
+	assert.Nil(t, err)
+}
+
+// TestRateLimitEndpointsAPIKey checks that an authenticated API key has a
+// bucket of its own, and that the other clients of its IP address share one.
+func TestRateLimitEndpointsAPIKey(t *testing.T) {
+	t.Parallel()
+
+	endpoints, err := endpoint.RateLimitEndpoints(
+		endpoint.MakeEndpoints(newService(t, mock.NameNoError)),
+		map[string]*ratelimit.Limiter{
+			"GetUserByID": ratelimit.New(ratelimit.Limit{Rate: rate.Every(time.Hour), Burst: 1}),
+		},
+	)
+	assert.Nil(t, err)
+
+	ctx := ratelimit.NewContext(context.TODO(), "ip:192.0.2.1")
+	request := entity.IDRequest{ID: mock.IDTest}
+
+	for _, name := range []string{"writer", "reader"} {
+		_, err = endpoints.GetUserByID(auth.NewAPIKeyContext(ctx, entity.APIKey{Name: name}), request)
+		assert.Nil(t, err)
+	}
+
+	// Unknown API keys are never authenticated, so they share the bucket of
+	// their IP address.
+	for _, secret := range []string{"sk_guess1", "sk_guess2"} {
+		_, err = endpoints.GetUserByID(auth.NewContext(ctx, secret), request)
+	}
+
+	assert.ErrorIs(t, err, kitratelimit.ErrLimited)
+}
+
+func TestRateLimitEndpointsUnknownMethod(t *testing.T) {
+	t.Parallel()
+
+	_, err := endpoint.RateLimitEndpoints(
+		endpoint.MakeEndpoints(newService(t, mock.NameNoError)),
+		map[string]*ratelimit.Limiter{
+			"GetUsers": ratelimit.New(ratelimit.Limit{Rate: 1, Burst: 1}),
+		},
+	)
+	assert.ErrorIs(t, err, endpoint.ErrUnknownMethod)
+	assert.ErrorContains(t, err, "GetUsers")
+}
@@ -0,0 +1,49 @@
+package endpoint
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the endpoints.
+const instrumentationName = "storage/internal/endpoint"
+
+// TracingMiddleware runs every call to method in a span named after it, e.g.
+// endpoint.GetUserByID. The span fails when the call returns an error or a
+// response whose Failed error is not nil.
+func TracingMiddleware(tracer trace.Tracer, method string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request any) (response any, err error) {
+			ctx, span := tracer.Start(ctx, "endpoint."+method)
+
+			defer func() {
+				failure := err
+				if failer, ok := response.(endpoint.Failer); ok && failure == nil {
+					failure = failer.Failed()
+				}
+
+				if failure != nil {
+					span.RecordError(failure)
+					span.SetStatus(codes.Error, failure.Error())
+				}
+
+				span.End()
+			}()
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// TraceEndpoints wraps every endpoint of endpoints in a TracingMiddleware
+// with a tracer of tp.
+func TraceEndpoints(endpoints Endpoints, tp trace.TracerProvider) Endpoints {
+	tracer := tp.Tracer(instrumentationName)
+
+	return wrapMethods(endpoints, func(method string) endpoint.Middleware {
+		return TracingMiddleware(tracer, method)
+	})
+}
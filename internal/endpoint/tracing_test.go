@@ -0,0 +1,68 @@
+package endpoint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		outResponse any
+		outErr      error
+		name        string
+		outStatus   codes.Code
+	}{
+		{
+			name:        mock.NameNoError,
+			outResponse: entity.IDErrorResponse{ID: mock.IDTest},
+			outStatus:   codes.Unset,
+		},
+		{
+			name:        "ErrorResponse",
+			outResponse: entity.IDErrorResponse{Err: errors.New(mock.ErrDatabaseClosed)},
+			outStatus:   codes.Error,
+		},
+		{
+			name:      "ErrorEndpoint",
+			outErr:    endpoint.ErrRequest,
+			outStatus: codes.Error,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			next := func(context.Context, any) (any, error) {
+				return tt.outResponse, tt.outErr
+			}
+
+			endpoints := endpoint.TraceEndpoints(endpoint.Endpoints{GetIDByUsername: next}, tp)
+
+			response, err := endpoints.GetIDByUsername(context.TODO(), entity.UsernameRequest{})
+
+			assert.Equal(t, tt.outResponse, response)
+			assert.ErrorIs(t, err, tt.outErr)
+
+			spans := recorder.Ended()
+			if assert.Len(t, spans, 1) {
+				assert.Equal(t, "endpoint.GetIDByUsername", spans[0].Name())
+				assert.Equal(t, tt.outStatus, spans[0].Status().Code)
+			}
+		})
+	}
+}
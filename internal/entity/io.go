@@ -3,6 +3,16 @@ package entity
 // EmptyRequest ...
 type EmptyRequest struct{}
 
+// ListUsersRequest ...
+type ListUsersRequest struct {
+	Cursor         string
+	SortBy         string
+	UsernamePrefix string
+	EmailDomain    string
+	Limit          int
+	Descending     bool
+}
+
 // IDRequest ...
 type IDRequest struct {
 	ID int `json:"id"`
@@ -26,33 +36,83 @@ type UsernamePasswordEmailRequest struct {
 	Email    string `json:"email"`
 }
 
+// UpdateUserRequest ...
+type UpdateUserRequest struct {
+	Patch UserPatch
+	ID    int
+}
+
 // ---
 
+// ErrorBody is the JSON body written for every failed request.
+type ErrorBody struct {
+	Err       string `json:"err"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
 // UsersErrorResponse ...
 type UsersErrorResponse struct {
-	Err   string `json:"err,omitempty"`
-	Users []User `json:"users"`
+	Err        error  `json:"-"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Users      []User `json:"users"`
+}
+
+// Failed implements endpoint.Failer.
+func (r UsersErrorResponse) Failed() error {
+	return r.Err
 }
 
 // UserErrorResponse ...
 type UserErrorResponse struct {
-	Err  string `json:"err,omitempty"`
-	User User   `json:"user"`
+	Err  error `json:"-"`
+	User User  `json:"user"`
+}
+
+// Failed implements endpoint.Failer.
+func (r UserErrorResponse) Failed() error {
+	return r.Err
 }
 
 // IDErrorResponse ...
 type IDErrorResponse struct {
-	Err string `json:"err,omitempty"`
-	ID  int    `json:"id"`
+	Err error `json:"-"`
+	ID  int   `json:"id"`
+}
+
+// Failed implements endpoint.Failer.
+func (r IDErrorResponse) Failed() error {
+	return r.Err
 }
 
 // ErrorResponse ...
 type ErrorResponse struct {
-	Err string `json:"err,omitempty"`
+	Err error `json:"-"`
+}
+
+// Failed implements endpoint.Failer.
+func (r ErrorResponse) Failed() error {
+	return r.Err
 }
 
 // RowsErrorResponse ...
 type RowsErrorResponse struct {
-	Err          string `json:"err,omitempty"`
-	RowsAffected int    `json:"rowsAffected"`
+	Err          error `json:"-"`
+	RowsAffected int   `json:"rowsAffected"`
+}
+
+// Failed implements endpoint.Failer.
+func (r RowsErrorResponse) Failed() error {
+	return r.Err
+}
+
+// CountErrorResponse ...
+type CountErrorResponse struct {
+	Err   error `json:"-"`
+	Count int   `json:"count"`
+}
+
+// Failed implements endpoint.Failer.
+func (r CountErrorResponse) Failed() error {
+	return r.Err
 }
@@ -8,6 +8,9 @@ const (
 	PasswordTest string = "password"
 	EmailTest    string = "email@email.com"
 
+	// LegacyHashTest is the unsalted SHA-256 hex digest of PasswordTest.
+	LegacyHashTest string = "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"
+
 	ErrDatabaseClosed string = "sql: database is closed"
 
 	NameNoError       string = "NoError"
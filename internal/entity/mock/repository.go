@@ -0,0 +1,71 @@
+package mock
+
+import (
+	"context"
+	"errors"
+
+	"storage/internal/entity"
+	"storage/internal/service"
+)
+
+// FailingRepository is a service.UserRepository and
+// service.LoginAttemptRepository whose every method fails as a closed
+// database would.
+type FailingRepository struct{}
+
+var errDatabaseClosed = errors.New(ErrDatabaseClosed)
+
+// ListUsers ...
+func (FailingRepository) ListUsers(context.Context, service.ListUsersQuery) ([]entity.User, error) {
+	return nil, errDatabaseClosed
+}
+
+// GetUserByID ...
+func (FailingRepository) GetUserByID(context.Context, int) (entity.User, error) {
+	return entity.User{}, errDatabaseClosed
+}
+
+// GetUserByUsername ...
+func (FailingRepository) GetUserByUsername(context.Context, string) (entity.User, error) {
+	return entity.User{}, errDatabaseClosed
+}
+
+// InsertUser ...
+func (FailingRepository) InsertUser(context.Context, entity.User) error {
+	return errDatabaseClosed
+}
+
+// UpdateUser ...
+func (FailingRepository) UpdateUser(context.Context, int, entity.UserPatch) (entity.User, error) {
+	return entity.User{}, errDatabaseClosed
+}
+
+// DeleteUser ...
+func (FailingRepository) DeleteUser(context.Context, int) (int, error) {
+	return 0, errDatabaseClosed
+}
+
+// CountLegacyPasswords ...
+func (FailingRepository) CountLegacyPasswords(context.Context) (int, error) {
+	return 0, errDatabaseClosed
+}
+
+// UpgradePassword ...
+func (FailingRepository) UpgradePassword(context.Context, int, string, string) (bool, error) {
+	return false, errDatabaseClosed
+}
+
+// GetLoginAttempts ...
+func (FailingRepository) GetLoginAttempts(context.Context, string) (entity.LoginAttempts, error) {
+	return entity.LoginAttempts{}, errDatabaseClosed
+}
+
+// RecordLoginFailure ...
+func (FailingRepository) RecordLoginFailure(context.Context, string, int, entity.LoginAttempts) (bool, error) {
+	return false, errDatabaseClosed
+}
+
+// ResetLoginAttempts ...
+func (FailingRepository) ResetLoginAttempts(context.Context, string) error {
+	return errDatabaseClosed
+}
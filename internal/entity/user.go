@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 // User ...
 type User struct {
 	Username string `json:"username"`
@@ -7,3 +9,29 @@ type User struct {
 	Email    string `json:"email"`
 	ID       int    `json:"id"`
 }
+
+// UserPatch holds the changes to apply to a user. Nil fields are left
+// untouched.
+type UserPatch struct {
+	Username *string
+	Password *string
+	Email    *string
+}
+
+// LoginAttempts holds the consecutive failed credential checks of a username,
+// when the last one was made and, once they locked it out, until when. A zero
+// LockedUntil means it is not locked.
+type LoginAttempts struct {
+	LockedUntil time.Time
+	LastFailure time.Time
+	Failures    int
+}
+
+// APIKey is a key callers of the API authenticate with. Only the hash of its
+// secret is kept.
+type APIKey struct {
+	CreatedAt time.Time `json:"createdAt"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"-"`
+	Scopes    []string  `json:"scopes"`
+}
@@ -0,0 +1,44 @@
+package grpctransport
+
+import (
+	"context"
+	"strings"
+
+	"storage/internal/auth"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// apiKeyFromMetadata puts the API key of the call, from its x-api-key or
+// authorization metadata, in the context for the endpoint AuthMiddleware.
+func apiKeyFromMetadata(ctx context.Context, md metadata.MD) context.Context {
+	var apiKey, authorization string
+
+	if values := md.Get(strings.ToLower(auth.Header)); len(values) > 0 {
+		apiKey = values[0]
+	}
+
+	if values := md.Get("authorization"); len(values) > 0 {
+		authorization = values[0]
+	}
+
+	return auth.NewContext(ctx, auth.Secret(apiKey, authorization))
+}
+
+// identityFromPeer puts the Identity of the client certificate of the call,
+// if any, in the context.
+func identityFromPeer(ctx context.Context, _ metadata.MD) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return ctx
+	}
+
+	return auth.NewIdentityContext(ctx, auth.IdentityFromCertificate(info.State.PeerCertificates[0]))
+}
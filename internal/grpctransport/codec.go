@@ -0,0 +1,178 @@
+package grpctransport
+
+import (
+	"context"
+	"fmt"
+
+	"storage/internal/entity"
+	"storage/internal/pb"
+)
+
+func decodeGetAllUsersRequest(_ context.Context, grpcReq any) (any, error) {
+	req, _ := grpcReq.(*pb.GetAllUsersRequest)
+
+	// Unlike the limit query parameter, limit cannot be left out, so 0 stands
+	// for the default page size.
+	if req.GetLimit() < 0 {
+		return nil, fmt.Errorf("%w: limit must not be negative", ErrInvalidArgument)
+	}
+
+	return entity.ListUsersRequest{
+		Cursor:         req.GetCursor(),
+		SortBy:         req.GetSort(),
+		UsernamePrefix: req.GetUsernamePrefix(),
+		EmailDomain:    req.GetEmailDomain(),
+		Limit:          int(req.GetLimit()),
+		Descending:     req.GetDescending(),
+	}, nil
+}
+
+func decodeGetUserByIDRequest(_ context.Context, grpcReq any) (any, error) {
+	req, _ := grpcReq.(*pb.GetUserByIDRequest)
+
+	return entity.IDRequest{ID: int(req.GetId())}, nil
+}
+
+func decodeGetUserByUsernameAndPasswordRequest(_ context.Context, grpcReq any) (any, error) {
+	req, _ := grpcReq.(*pb.GetUserByUsernameAndPasswordRequest)
+
+	return entity.UsernamePasswordRequest{Username: req.GetUsername(), Password: req.GetPassword()}, nil
+}
+
+func decodeGetIDByUsernameRequest(_ context.Context, grpcReq any) (any, error) {
+	req, _ := grpcReq.(*pb.GetIDByUsernameRequest)
+
+	if req.GetUsername() == "" {
+		return nil, fmt.Errorf("%w: username is required", ErrInvalidArgument)
+	}
+
+	return entity.UsernameRequest{Username: req.GetUsername()}, nil
+}
+
+func decodeInsertUserRequest(_ context.Context, grpcReq any) (any, error) {
+	req, _ := grpcReq.(*pb.InsertUserRequest)
+
+	return entity.UsernamePasswordEmailRequest{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+		Email:    req.GetEmail(),
+	}, nil
+}
+
+// decodeUpdateUserRequest leaves the fields that are not set untouched, as
+// the members missing from a merge patch, and rejects empty ones.
+func decodeUpdateUserRequest(_ context.Context, grpcReq any) (any, error) {
+	req, _ := grpcReq.(*pb.UpdateUserRequest)
+
+	patch := entity.UserPatch{
+		Username: req.Username,
+		Password: req.Password,
+		Email:    req.Email,
+	}
+
+	for name, field := range map[string]*string{
+		"username": patch.Username,
+		"password": patch.Password,
+		"email":    patch.Email,
+	} {
+		if field != nil && *field == "" {
+			return nil, fmt.Errorf("%w: %s must be a non-empty string", ErrInvalidArgument, name)
+		}
+	}
+
+	return entity.UpdateUserRequest{ID: int(req.GetId()), Patch: patch}, nil
+}
+
+func decodeDeleteUserRequest(_ context.Context, grpcReq any) (any, error) {
+	req, _ := grpcReq.(*pb.DeleteUserRequest)
+
+	return entity.IDRequest{ID: int(req.GetId())}, nil
+}
+
+func decodeCountLegacyPasswordsRequest(_ context.Context, _ any) (any, error) {
+	return entity.EmptyRequest{}, nil
+}
+
+func decodeUnlockUserRequest(_ context.Context, grpcReq any) (any, error) {
+	req, _ := grpcReq.(*pb.UnlockUserRequest)
+
+	return entity.IDRequest{ID: int(req.GetId())}, nil
+}
+
+// ---
+
+func encodeGetAllUsersReply(_ context.Context, response any) (any, error) {
+	resp, _ := response.(entity.UsersErrorResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	users := make([]*pb.User, 0, len(resp.Users))
+	for i := range resp.Users {
+		users = append(users, userToPB(resp.Users[i]))
+	}
+
+	return &pb.GetAllUsersReply{Users: users, NextCursor: resp.NextCursor}, nil
+}
+
+func encodeUserReply(_ context.Context, response any) (any, error) {
+	resp, _ := response.(entity.UserErrorResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	return &pb.UserReply{User: userToPB(resp.User)}, nil
+}
+
+func encodeGetIDByUsernameReply(_ context.Context, response any) (any, error) {
+	resp, _ := response.(entity.IDErrorResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	return &pb.GetIDByUsernameReply{Id: int64(resp.ID)}, nil
+}
+
+func encodeInsertUserReply(_ context.Context, response any) (any, error) {
+	resp, _ := response.(entity.ErrorResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	return &pb.InsertUserReply{}, nil
+}
+
+func encodeDeleteUserReply(_ context.Context, response any) (any, error) {
+	resp, _ := response.(entity.RowsErrorResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	return &pb.DeleteUserReply{RowsAffected: int64(resp.RowsAffected)}, nil
+}
+
+func encodeCountLegacyPasswordsReply(_ context.Context, response any) (any, error) {
+	resp, _ := response.(entity.CountErrorResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	return &pb.CountLegacyPasswordsReply{Count: int64(resp.Count)}, nil
+}
+
+func encodeUnlockUserReply(_ context.Context, response any) (any, error) {
+	resp, _ := response.(entity.ErrorResponse)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	return &pb.UnlockUserReply{}, nil
+}
+
+func userToPB(user entity.User) *pb.User {
+	return &pb.User{
+		Id:       int64(user.ID),
+		Username: user.Username,
+		Email:    user.Email,
+	}
+}
@@ -0,0 +1,55 @@
+package grpctransport
+
+import (
+	"context"
+	"errors"
+
+	"storage/internal/auth"
+	"storage/internal/service"
+
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// internalMessage is the message of the statuses of the errors without a code
+// of their own, whose text may hold internal details.
+const internalMessage = "internal error"
+
+// statusError returns err as a gRPC status with the code of err and its
+// message, or internalMessage for codes.Internal.
+func statusError(err error) error {
+	code := errorCode(err)
+	if code == codes.Internal {
+		return status.Error(code, internalMessage)
+	}
+
+	return status.Error(code, err.Error())
+}
+
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, ErrInvalidArgument), errors.Is(err, service.ErrInvalidListOptions):
+		return codes.InvalidArgument
+	case errors.Is(err, service.ErrUserNotFound):
+		return codes.NotFound
+	case errors.Is(err, service.ErrUsernameTaken), errors.Is(err, service.ErrEmailTaken):
+		return codes.AlreadyExists
+	case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, auth.ErrUnauthenticated):
+		return codes.Unauthenticated
+	case errors.Is(err, auth.ErrForbidden):
+		return codes.PermissionDenied
+	case errors.Is(err, service.ErrUserLocked):
+		return codes.FailedPrecondition
+	case errors.Is(err, kitratelimit.ErrLimited):
+		return codes.ResourceExhausted
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,54 @@
+package grpctransport
+
+import (
+	"context"
+	"time"
+
+	"storage/internal/auth"
+	"storage/internal/requestid"
+
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+type callBeginKey struct{}
+
+// LoggingOptions returns the server options that log every call with its
+// method, status code, latency, request ID, client certificate identity and
+// error, if any, as the HTTP transport LoggingOptions do for requests.
+func LoggingOptions(logger log.Logger) []kitgrpc.ServerOption {
+	return []kitgrpc.ServerOption{
+		kitgrpc.ServerBefore(func(ctx context.Context, _ metadata.MD) context.Context {
+			return context.WithValue(ctx, callBeginKey{}, time.Now())
+		}),
+		kitgrpc.ServerFinalizer(func(ctx context.Context, err error) {
+			begin, ok := ctx.Value(callBeginKey{}).(time.Time)
+			if !ok {
+				return
+			}
+
+			method, _ := grpc.Method(ctx)
+
+			code := codes.OK
+			if err != nil {
+				code = errorCode(err)
+			}
+
+			keyvals := []any{
+				"method", method,
+				"code", code.String(),
+				"took", time.Since(begin),
+				"request_id", requestid.FromContext(ctx),
+			}
+
+			if identity, ok := auth.IdentityFromContext(ctx); ok {
+				keyvals = append(keyvals, "client", identity.Name)
+			}
+
+			_ = logger.Log(append(keyvals, "err", err)...)
+		}),
+	}
+}
@@ -0,0 +1,51 @@
+package grpctransport
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/netip"
+	"strconv"
+
+	"storage/internal/ratelimit"
+
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// RetryAfterKey is the metadata key telling a rate limited client how many
+// seconds to wait, as the Retry-After header does over HTTP.
+const RetryAfterKey = "retry-after"
+
+// RateLimitOptions returns the server options that key the client of every
+// call by its IP address, for the endpoint RateLimitMiddleware, which keys
+// authenticated clients by their API key instead. The x-forwarded-for
+// metadata tells the address of the client only for calls from
+// trustedProxies.
+func RateLimitOptions(trustedProxies []netip.Prefix) []kitgrpc.ServerOption {
+	return []kitgrpc.ServerOption{
+		kitgrpc.ServerBefore(func(ctx context.Context, md metadata.MD) context.Context {
+			var addr string
+			if p, ok := peer.FromContext(ctx); ok {
+				addr = p.Addr.String()
+			}
+
+			addr = ratelimit.ClientAddr(addr, md.Get("x-forwarded-for"), trustedProxies)
+
+			return ratelimit.NewContext(ctx, ratelimit.ClientKey(addr))
+		}),
+	}
+}
+
+// retryAfterMetadata returns the retry-after metadata of err, or nil when it
+// did not come from the rate limiter.
+func retryAfterMetadata(err error) metadata.MD {
+	var limitErr *ratelimit.Error
+
+	if !errors.As(err, &limitErr) || limitErr.RetryAfter <= 0 {
+		return nil
+	}
+
+	return metadata.Pairs(RetryAfterKey, strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+}
@@ -0,0 +1,168 @@
+// Package grpctransport serves the endpoints over gRPC, as the Storage
+// service of package pb, next to the HTTP transport.
+package grpctransport
+
+import (
+	"context"
+
+	"storage/internal/endpoint"
+	"storage/internal/pb"
+	"storage/internal/requestid"
+
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// server adapts a go-kit gRPC handler per endpoint to pb.StorageServer.
+type server struct {
+	pb.UnimplementedStorageServer
+	getAllUsers                  kitgrpc.Handler
+	getUserByID                  kitgrpc.Handler
+	getUserByUsernameAndPassword kitgrpc.Handler
+	getIDByUsername              kitgrpc.Handler
+	insertUser                   kitgrpc.Handler
+	updateUser                   kitgrpc.Handler
+	deleteUser                   kitgrpc.Handler
+	countLegacyPasswords         kitgrpc.Handler
+	unlockUser                   kitgrpc.Handler
+}
+
+// RequestIDKey is the metadata key holding the request ID, the gRPC
+// counterpart of the X-Request-ID header.
+const RequestIDKey = "x-request-id"
+
+// NewServer returns the pb.StorageServer serving endpoints. Like the HTTP
+// transport, it takes the request ID from the x-request-id metadata, or
+// generates one, and echoes it in the response header, and puts the API key
+// of the x-api-key or authorization metadata, and the Identity of the client
+// certificate, in the context.
+func NewServer(endpoints endpoint.Endpoints, options ...kitgrpc.ServerOption) pb.StorageServer {
+	options = append(
+		[]kitgrpc.ServerOption{kitgrpc.ServerBefore(requestIDFromMetadata, apiKeyFromMetadata, identityFromPeer)},
+		options...,
+	)
+
+	return &server{
+		getAllUsers: kitgrpc.NewServer(
+			endpoints.GetAllUsers, decodeGetAllUsersRequest, encodeGetAllUsersReply, options...,
+		),
+		getUserByID: kitgrpc.NewServer(
+			endpoints.GetUserByID, decodeGetUserByIDRequest, encodeUserReply, options...,
+		),
+		getUserByUsernameAndPassword: kitgrpc.NewServer(
+			endpoints.GetUserByUsernameAndPassword, decodeGetUserByUsernameAndPasswordRequest, encodeUserReply, options...,
+		),
+		getIDByUsername: kitgrpc.NewServer(
+			endpoints.GetIDByUsername, decodeGetIDByUsernameRequest, encodeGetIDByUsernameReply, options...,
+		),
+		insertUser: kitgrpc.NewServer(
+			endpoints.InsertUser, decodeInsertUserRequest, encodeInsertUserReply, options...,
+		),
+		updateUser: kitgrpc.NewServer(
+			endpoints.UpdateUser, decodeUpdateUserRequest, encodeUserReply, options...,
+		),
+		deleteUser: kitgrpc.NewServer(
+			endpoints.DeleteUser, decodeDeleteUserRequest, encodeDeleteUserReply, options...,
+		),
+		countLegacyPasswords: kitgrpc.NewServer(
+			endpoints.CountLegacyPasswords, decodeCountLegacyPasswordsRequest, encodeCountLegacyPasswordsReply, options...,
+		),
+		unlockUser: kitgrpc.NewServer(
+			endpoints.UnlockUser, decodeUnlockUserRequest, encodeUnlockUserReply, options...,
+		),
+	}
+}
+
+// GetAllUsers ...
+func (s *server) GetAllUsers(ctx context.Context, req *pb.GetAllUsersRequest) (*pb.GetAllUsersReply, error) {
+	return serve[*pb.GetAllUsersReply](ctx, s.getAllUsers, req)
+}
+
+// GetUserByID ...
+func (s *server) GetUserByID(ctx context.Context, req *pb.GetUserByIDRequest) (*pb.UserReply, error) {
+	return serve[*pb.UserReply](ctx, s.getUserByID, req)
+}
+
+// GetUserByUsernameAndPassword ...
+func (s *server) GetUserByUsernameAndPassword(
+	ctx context.Context,
+	req *pb.GetUserByUsernameAndPasswordRequest,
+) (*pb.UserReply, error) {
+	return serve[*pb.UserReply](ctx, s.getUserByUsernameAndPassword, req)
+}
+
+// GetIDByUsername ...
+func (s *server) GetIDByUsername(
+	ctx context.Context,
+	req *pb.GetIDByUsernameRequest,
+) (*pb.GetIDByUsernameReply, error) {
+	return serve[*pb.GetIDByUsernameReply](ctx, s.getIDByUsername, req)
+}
+
+// InsertUser ...
+func (s *server) InsertUser(ctx context.Context, req *pb.InsertUserRequest) (*pb.InsertUserReply, error) {
+	return serve[*pb.InsertUserReply](ctx, s.insertUser, req)
+}
+
+// UpdateUser ...
+func (s *server) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserReply, error) {
+	return serve[*pb.UserReply](ctx, s.updateUser, req)
+}
+
+// DeleteUser ...
+func (s *server) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserReply, error) {
+	return serve[*pb.DeleteUserReply](ctx, s.deleteUser, req)
+}
+
+// CountLegacyPasswords ...
+func (s *server) CountLegacyPasswords(
+	ctx context.Context,
+	req *pb.CountLegacyPasswordsRequest,
+) (*pb.CountLegacyPasswordsReply, error) {
+	return serve[*pb.CountLegacyPasswordsReply](ctx, s.countLegacyPasswords, req)
+}
+
+// UnlockUser ...
+func (s *server) UnlockUser(ctx context.Context, req *pb.UnlockUserRequest) (*pb.UnlockUserReply, error) {
+	return serve[*pb.UnlockUserReply](ctx, s.unlockUser, req)
+}
+
+// serve runs handler on req, echoes the request ID in the response header
+// and turns the error, if any, into a gRPC status, telling rate limited
+// clients when to retry.
+func serve[Reply any](ctx context.Context, handler kitgrpc.Handler, req any) (reply Reply, err error) {
+	ctx, response, err := handler.ServeGRPC(ctx, req)
+
+	if id := requestid.FromContext(ctx); id != "" {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+	}
+
+	if err != nil {
+		if md := retryAfterMetadata(err); md != nil {
+			_ = grpc.SetHeader(ctx, md)
+		}
+
+		return reply, statusError(err)
+	}
+
+	reply, _ = response.(Reply)
+
+	return reply, nil
+}
+
+// requestIDFromMetadata puts the request ID of md into ctx, or a new one
+// when it is missing or not a valid request ID.
+func requestIDFromMetadata(ctx context.Context, md metadata.MD) context.Context {
+	var id string
+
+	if values := md.Get(RequestIDKey); len(values) > 0 {
+		id = values[0]
+	}
+
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+
+	return requestid.NewContext(ctx, id)
+}
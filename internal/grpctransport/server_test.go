@@ -0,0 +1,599 @@
+package grpctransport_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"storage/internal/auth"
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/grpctransport"
+	"storage/internal/password"
+	"storage/internal/pb"
+	"storage/internal/ratelimit"
+	"storage/internal/repository"
+	"storage/internal/service"
+	"storage/internal/tlsconfig"
+	"storage/internal/tlsconfig/tlstest"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+const otherUsernameTest = "other"
+
+// newService returns a service over a memory repository holding the mock
+// user, with ID mock.IDTest, and the other user.
+func newService(t *testing.T) service.Service {
+	t.Helper()
+
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+
+	passwordHashed, err := hasher.Hash(mock.PasswordTest)
+	assert.Nil(t, err)
+
+	repo := repository.NewMemory()
+
+	for _, user := range []entity.User{
+		{Username: mock.UsernameTest, Password: passwordHashed, Email: mock.EmailTest},
+		{Username: otherUsernameTest, Password: passwordHashed, Email: "other@other.com"},
+	} {
+		err = repo.InsertUser(context.TODO(), user)
+		assert.Nil(t, err)
+	}
+
+	return service.GetService(repo, hasher)
+}
+
+// newClient serves the endpoints of newService over an in-memory connection
+// and returns a client of it.
+func newClient(t *testing.T) pb.StorageClient {
+	t.Helper()
+
+	return dial(t, grpctransport.NewServer(endpoint.MakeEndpoints(newService(t))))
+}
+
+// dial serves storageServer over an in-memory connection and returns a
+// client of it.
+func dial(t *testing.T, storageServer pb.StorageServer) pb.StorageClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer()
+	pb.RegisterStorageServer(server, storageServer)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.Nil(t, err)
+
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewStorageClient(conn)
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		call     func(pb.StorageClient) (proto.Message, error)
+		outReply proto.Message
+		name     string
+		outCode  codes.Code
+	}{
+		{
+			name: "GetUserByID",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.GetUserByID(context.TODO(), &pb.GetUserByIDRequest{Id: int64(mock.IDTest)})
+			},
+			outCode: codes.OK,
+		},
+		{
+			name: "GetUserByIDNotFound",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.GetUserByID(context.TODO(), &pb.GetUserByIDRequest{Id: int64(mock.IDTest + 9)})
+			},
+			outCode: codes.NotFound,
+		},
+		{
+			name: "GetAllUsersInvalidSort",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.GetAllUsers(context.TODO(), &pb.GetAllUsersRequest{Sort: "password"})
+			},
+			outCode: codes.InvalidArgument,
+		},
+		{
+			name: "GetAllUsersNegativeLimit",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.GetAllUsers(context.TODO(), &pb.GetAllUsersRequest{Limit: -1})
+			},
+			outCode: codes.InvalidArgument,
+		},
+		{
+			name: "GetUserByUsernameAndPasswordInvalidCredentials",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.GetUserByUsernameAndPassword(context.TODO(), &pb.GetUserByUsernameAndPasswordRequest{
+					Username: mock.UsernameTest,
+					Password: "wrong",
+				})
+			},
+			outCode: codes.Unauthenticated,
+		},
+		{
+			name: "GetIDByUsername",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.GetIDByUsername(context.TODO(), &pb.GetIDByUsernameRequest{Username: otherUsernameTest})
+			},
+			outReply: &pb.GetIDByUsernameReply{Id: int64(mock.IDTest + 1)},
+			outCode:  codes.OK,
+		},
+		{
+			name: "GetIDByUsernameEmpty",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.GetIDByUsername(context.TODO(), &pb.GetIDByUsernameRequest{})
+			},
+			outCode: codes.InvalidArgument,
+		},
+		{
+			name: "InsertUser",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.InsertUser(context.TODO(), &pb.InsertUserRequest{
+					Username: "new",
+					Password: mock.PasswordTest,
+					Email:    "new@new.com",
+				})
+			},
+			outReply: &pb.InsertUserReply{},
+			outCode:  codes.OK,
+		},
+		{
+			name: "InsertUserUsernameTaken",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.InsertUser(context.TODO(), &pb.InsertUserRequest{
+					Username: mock.UsernameTest,
+					Password: mock.PasswordTest,
+					Email:    "new@new.com",
+				})
+			},
+			outCode: codes.AlreadyExists,
+		},
+		{
+			name: "UpdateUserEmptyEmail",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.UpdateUser(context.TODO(), &pb.UpdateUserRequest{
+					Id:    int64(mock.IDTest),
+					Email: proto.String(""),
+				})
+			},
+			outCode: codes.InvalidArgument,
+		},
+		{
+			name: "DeleteUser",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.DeleteUser(context.TODO(), &pb.DeleteUserRequest{Id: int64(mock.IDTest + 1)})
+			},
+			outReply: &pb.DeleteUserReply{RowsAffected: 1},
+			outCode:  codes.OK,
+		},
+		{
+			name: "UnlockUser",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.UnlockUser(context.TODO(), &pb.UnlockUserRequest{Id: int64(mock.IDTest)})
+			},
+			outReply: &pb.UnlockUserReply{},
+			outCode:  codes.OK,
+		},
+		{
+			name: "UnlockUserNotFound",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.UnlockUser(context.TODO(), &pb.UnlockUserRequest{Id: int64(mock.IDTest + 9)})
+			},
+			outCode: codes.NotFound,
+		},
+		{
+			name: "CountLegacyPasswords",
+			call: func(c pb.StorageClient) (proto.Message, error) {
+				return c.CountLegacyPasswords(context.TODO(), &pb.CountLegacyPasswordsRequest{})
+			},
+			outReply: &pb.CountLegacyPasswordsReply{},
+			outCode:  codes.OK,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			reply, err := tt.call(newClient(t))
+
+			assert.Equal(t, tt.outCode, status.Code(err))
+
+			if tt.outReply != nil {
+				assert.True(t, proto.Equal(tt.outReply, reply), "reply: %v", reply)
+			}
+		})
+	}
+}
+
+func TestGetUserByID(t *testing.T) {
+	t.Parallel()
+
+	reply, err := newClient(t).GetUserByID(context.TODO(), &pb.GetUserByIDRequest{Id: int64(mock.IDTest)})
+	assert.Nil(t, err)
+
+	assert.Equal(t, int64(mock.IDTest), reply.GetUser().GetId())
+	assert.Equal(t, mock.UsernameTest, reply.GetUser().GetUsername())
+	assert.Equal(t, mock.EmailTest, reply.GetUser().GetEmail())
+}
+
+func TestGetAllUsers(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(t)
+
+	req := &pb.GetAllUsersRequest{Sort: "username", Descending: true, Limit: 1}
+
+	reply, err := c.GetAllUsers(context.TODO(), req)
+	assert.Nil(t, err)
+
+	if assert.Len(t, reply.GetUsers(), 1) {
+		assert.Equal(t, mock.UsernameTest, reply.GetUsers()[0].GetUsername())
+	}
+
+	req.Cursor = reply.GetNextCursor()
+
+	reply, err = c.GetAllUsers(context.TODO(), req)
+	assert.Nil(t, err)
+	assert.Empty(t, reply.GetNextCursor())
+
+	if assert.Len(t, reply.GetUsers(), 1) {
+		assert.Equal(t, otherUsernameTest, reply.GetUsers()[0].GetUsername())
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	t.Parallel()
+
+	c := newClient(t)
+
+	reply, err := c.UpdateUser(context.TODO(), &pb.UpdateUserRequest{
+		Id:    int64(mock.IDTest),
+		Email: proto.String("new@new.com"),
+	})
+	assert.Nil(t, err)
+
+	// The username is left untouched.
+	assert.Equal(t, mock.UsernameTest, reply.GetUser().GetUsername())
+	assert.Equal(t, "new@new.com", reply.GetUser().GetEmail())
+
+	_, err = c.GetUserByUsernameAndPassword(context.TODO(), &pb.GetUserByUsernameAndPasswordRequest{
+		Username: mock.UsernameTest,
+		Password: mock.PasswordTest,
+	})
+	assert.Nil(t, err)
+}
+
+func TestInternalError(t *testing.T) {
+	t.Parallel()
+
+	svc := service.GetService(mock.FailingRepository{}, password.NewBcrypt(bcrypt.MinCost))
+	c := dial(t, grpctransport.NewServer(endpoint.MakeEndpoints(svc)))
+
+	_, err := c.GetUserByID(context.TODO(), &pb.GetUserByIDRequest{Id: int64(mock.IDTest)})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.NotContains(t, status.Convert(err).Message(), mock.ErrDatabaseClosed)
+}
+
+func TestLoggingOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		inID      string
+		inReq     *pb.GetUserByIDRequest
+		outFields []string
+	}{
+		{
+			name:  "GetUserByID",
+			inID:  "abc-123",
+			inReq: &pb.GetUserByIDRequest{Id: int64(mock.IDTest)},
+			outFields: []string{
+				"method=/storage.v1.Storage/GetUserByID", "code=OK", "took=", "request_id=abc-123", "err=null",
+			},
+		},
+		{
+			name:  "GetUserByIDNotFound",
+			inReq: &pb.GetUserByIDRequest{Id: 99},
+			outFields: []string{
+				"code=NotFound", `err="user not found"`,
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			c := dial(t, grpctransport.NewServer(
+				endpoint.MakeEndpoints(newService(t)),
+				grpctransport.LoggingOptions(log.NewLogfmtLogger(log.NewSyncWriter(&buf)))...,
+			))
+
+			ctx := context.TODO()
+			if tt.inID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, grpctransport.RequestIDKey, tt.inID)
+			}
+
+			_, _ = c.GetUserByID(ctx, tt.inReq)
+
+			for _, field := range tt.outFields {
+				assert.Contains(t, buf.String(), field)
+			}
+		})
+	}
+}
+
+func TestTracingOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		inRepo        service.UserRepository
+		name          string
+		inID          int64
+		inTraceparent string
+		outTraceID    string
+		outCode       codes.Code
+		outStatus     otelcodes.Code
+	}{
+		{
+			name:          mock.NameNoError,
+			inID:          int64(mock.IDTest),
+			inTraceparent: "00-4bf92f3577b34da6a3ce929b0e0e4736-00f067aa0ba902b7-01",
+			outTraceID:    "4bf92f3577b34da6a3ce929b0e0e4736",
+			outCode:       codes.OK,
+			outStatus:     otelcodes.Unset,
+		},
+		{
+			name:      "GetUserByIDNotFound",
+			inID:      99,
+			outCode:   codes.NotFound,
+			outStatus: otelcodes.Unset,
+		},
+		{
+			name:      mock.NameErrorDBClosed,
+			inRepo:    mock.FailingRepository{},
+			inID:      int64(mock.IDTest),
+			outCode:   codes.Internal,
+			outStatus: otelcodes.Error,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := newService(t)
+			if tt.inRepo != nil {
+				svc = service.GetService(tt.inRepo, nil)
+			}
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			c := dial(t, grpctransport.NewServer(endpoint.MakeEndpoints(svc), grpctransport.TracingOptions(tp)...))
+
+			ctx := context.TODO()
+			if tt.inTraceparent != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "traceparent", tt.inTraceparent)
+			}
+
+			_, err := c.GetUserByID(ctx, &pb.GetUserByIDRequest{Id: tt.inID})
+			assert.Equal(t, tt.outCode, status.Code(err))
+
+			spans := recorder.Ended()
+			if assert.Len(t, spans, 1) {
+				assert.Equal(t, "storage.v1.Storage/GetUserByID", spans[0].Name())
+				assert.Contains(t, spans[0].Attributes(), attribute.String("rpc.method", "GetUserByID"))
+				assert.Contains(t, spans[0].Attributes(), attribute.Int("rpc.grpc.status_code", int(tt.outCode)))
+				assert.Equal(t, tt.outStatus, spans[0].Status().Code)
+
+				if tt.outTraceID != "" {
+					assert.Equal(t, tt.outTraceID, spans[0].SpanContext().TraceID().String())
+					assert.True(t, spans[0].Parent().IsRemote())
+				}
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name  string
+		inID  string
+		outID string
+		inReq *pb.GetUserByIDRequest
+	}{
+		{name: "Given", inID: "abc-123", outID: "abc-123", inReq: &pb.GetUserByIDRequest{Id: int64(mock.IDTest)}},
+		{name: "Generated", inReq: &pb.GetUserByIDRequest{Id: int64(mock.IDTest)}},
+		{name: "TooLong", inID: strings.Repeat("a", 129), inReq: &pb.GetUserByIDRequest{Id: int64(mock.IDTest)}},
+		{name: "Failed", inID: "abc-123", outID: "abc-123", inReq: &pb.GetUserByIDRequest{Id: 99}},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.TODO()
+			if tt.inID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, grpctransport.RequestIDKey, tt.inID)
+			}
+
+			var header metadata.MD
+
+			_, _ = newClient(t).GetUserByID(ctx, tt.inReq, grpc.Header(&header))
+
+			ids := header.Get(grpctransport.RequestIDKey)
+			if !assert.Len(t, ids, 1) {
+				return
+			}
+
+			if tt.outID != "" {
+				assert.Equal(t, tt.outID, ids[0])
+			} else {
+				assert.NotEmpty(t, ids[0])
+				assert.NotEqual(t, tt.inID, ids[0])
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	endpoints, err := endpoint.RateLimitEndpoints(
+		endpoint.MakeEndpoints(newService(t)),
+		map[string]*ratelimit.Limiter{
+			"GetUserByID": ratelimit.New(ratelimit.Limit{Rate: rate.Every(time.Minute), Burst: 1}),
+		},
+	)
+	assert.Nil(t, err)
+
+	c := dial(t, grpctransport.NewServer(endpoints, grpctransport.RateLimitOptions(nil)...))
+
+	req := &pb.GetUserByIDRequest{Id: int64(mock.IDTest)}
+	ctx := metadata.AppendToOutgoingContext(context.TODO(), "x-api-key", "a")
+
+	_, err = c.GetUserByID(ctx, req)
+	assert.Nil(t, err)
+
+	var header metadata.MD
+
+	_, err = c.GetUserByID(ctx, req, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"60"}, header.Get(grpctransport.RetryAfterKey))
+
+	// An unverified API key does not get a bucket of its own.
+	_, err = c.GetUserByID(metadata.AppendToOutgoingContext(context.TODO(), "x-api-key", "b"), req)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	keys := repository.NewMemory()
+
+	err := keys.InsertAPIKey(context.TODO(), entity.APIKey{
+		Name:   "reader",
+		Hash:   auth.Hash("sk_reader"),
+		Scopes: []string{auth.ScopeRead},
+	})
+	assert.Nil(t, err)
+
+	c := dial(t, grpctransport.NewServer(
+		endpoint.AuthenticateEndpoints(
+			endpoint.AuthorizeEndpoints(endpoint.MakeEndpoints(newService(t))),
+			auth.NewAuthenticator(keys),
+		),
+	))
+
+	req := &pb.GetUserByIDRequest{Id: int64(mock.IDTest)}
+
+	_, err = c.GetUserByID(context.TODO(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = c.GetUserByID(metadata.AppendToOutgoingContext(context.TODO(), "x-api-key", "sk_reader"), req)
+	assert.Nil(t, err)
+
+	ctx := metadata.AppendToOutgoingContext(context.TODO(), "authorization", "Bearer sk_reader")
+
+	_, err = c.GetUserByID(ctx, req)
+	assert.Nil(t, err)
+
+	_, err = c.DeleteUser(ctx, &pb.DeleteUserRequest{Id: int64(mock.IDTest)})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestIdentity(t *testing.T) {
+	t.Parallel()
+
+	ca := tlstest.NewCA(t)
+	dir := t.TempDir()
+	serverCert := ca.Server(t)
+
+	reloader, err := tlsconfig.New(tlsconfig.Files{
+		CertFile:     tlstest.WriteFile(t, dir, "tls.crt", serverCert.CertPEM),
+		KeyFile:      tlstest.WriteFile(t, dir, "tls.key", serverCert.KeyPEM),
+		ClientCAFile: tlstest.WriteFile(t, dir, "ca.crt", ca.PEM),
+	})
+	assert.Nil(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	// GetUserByID answers with the identity of the caller as username.
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(reloader.Config())))
+	pb.RegisterStorageServer(server, grpctransport.NewServer(endpoint.Endpoints{
+		GetUserByID: func(ctx context.Context, _ any) (any, error) {
+			identity, _ := auth.IdentityFromContext(ctx)
+
+			return entity.UserErrorResponse{User: entity.User{Username: identity.Name}}, nil
+		},
+	}))
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			RootCAs:      ca.Pool(),
+			ServerName:   "localhost",
+			Certificates: []tls.Certificate{ca.Client(t, "billing").TLSCertificate(t)},
+			MinVersion:   tls.VersionTLS12,
+		})),
+	)
+	assert.Nil(t, err)
+
+	t.Cleanup(func() { conn.Close() })
+
+	reply, err := pb.NewStorageClient(conn).GetUserByID(context.TODO(), &pb.GetUserByIDRequest{Id: 1})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "billing", reply.GetUser().GetUsername())
+	}
+}
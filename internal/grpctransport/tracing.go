@@ -0,0 +1,109 @@
+package grpctransport
+
+import (
+	"context"
+	"strings"
+
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+type serverSpanKey struct{}
+
+// instrumentationName names the tracer of the transport.
+const instrumentationName = "storage/internal/grpctransport"
+
+// metadataCarrier lets a propagator read the incoming metadata of a call.
+type metadataCarrier metadata.MD
+
+// TracingOptions returns the server options that serve every call in a span
+// named after its method, e.g. "storage.v1.Storage/GetUserByID", with a
+// tracer of tp, as the HTTP transport TracingOptions do for requests. The
+// span continues the trace of the W3C traceparent metadata of the call, if
+// any, and fails on the codes of server errors, e.g. INTERNAL.
+func TracingOptions(tp trace.TracerProvider) []kitgrpc.ServerOption {
+	tracer := tp.Tracer(instrumentationName)
+	propagator := propagation.TraceContext{}
+
+	return []kitgrpc.ServerOption{
+		kitgrpc.ServerBefore(func(ctx context.Context, md metadata.MD) context.Context {
+			ctx = propagator.Extract(ctx, metadataCarrier(md))
+
+			fullMethod, _ := grpc.Method(ctx)
+			name := strings.TrimPrefix(fullMethod, "/")
+			attributes := []attribute.KeyValue{semconv.RPCSystemGRPC}
+
+			if service, method, ok := strings.Cut(name, "/"); ok {
+				attributes = append(attributes, semconv.RPCServiceKey.String(service), semconv.RPCMethodKey.String(method))
+			}
+
+			ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+
+			return context.WithValue(ctx, serverSpanKey{}, span)
+		}),
+		kitgrpc.ServerFinalizer(func(ctx context.Context, err error) {
+			span, ok := ctx.Value(serverSpanKey{}).(trace.Span)
+			if !ok {
+				return
+			}
+
+			code := codes.OK
+			if err != nil {
+				code = errorCode(err)
+				span.RecordError(err)
+			}
+
+			span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+
+			if isServerError(code) {
+				span.SetStatus(otelcodes.Error, code.String())
+			}
+
+			span.End()
+		}),
+	}
+}
+
+// isServerError reports whether code tells of a failure of the server rather
+// than of the call, as 5xx status codes do over HTTP.
+func isServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable,
+		codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// Get ...
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Set ...
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys ...
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
@@ -0,0 +1,204 @@
+// Package health serves the liveness, readiness and status endpoints used by
+// orchestrators and operators.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"storage/internal/migrate"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+)
+
+// Checker answers the health endpoints. Its database and migrator are nil
+// when users are not stored in a database.
+type Checker struct {
+	startedAt time.Time
+	logger    log.Logger
+	db        *sql.DB
+	migrator  *migrate.Migrator
+	version   string
+	draining  atomic.Bool
+}
+
+// StatusResponse is the body of /readyz and /healthz.
+type StatusResponse struct {
+	Status string `json:"status"`
+	Err    string `json:"err,omitempty"`
+}
+
+// DetailsResponse is the body of /status.
+type DetailsResponse struct {
+	StartedAt  time.Time      `json:"startedAt"`
+	DB         *DBStats       `json:"db,omitempty"`
+	Migrations *Migrations    `json:"migrations,omitempty"`
+	Version    string         `json:"version"`
+	Uptime     string         `json:"uptime"`
+	Ready      StatusResponse `json:"ready"`
+}
+
+// DBStats holds the connection pool figures of sql.DBStats.
+type DBStats struct {
+	MaxOpenConnections int    `json:"maxOpenConnections"`
+	OpenConnections    int    `json:"openConnections"`
+	InUse              int    `json:"inUse"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"waitCount"`
+	WaitDuration       string `json:"waitDuration"`
+	MaxIdleClosed      int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed  int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed  int64  `json:"maxLifetimeClosed"`
+}
+
+// Migrations tells how many known migrations are pending.
+type Migrations struct {
+	Err     string `json:"err,omitempty"`
+	Pending int    `json:"pending"`
+}
+
+const (
+	StatusOK       = "ok"
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+	StatusDraining = "draining"
+
+	// The reasons a check fails for, which the health endpoints tell rather
+	// than the errors behind them, as they are served without
+	// authentication.
+	ReasonDatabaseUnavailable   = "database unavailable"
+	ReasonMigrationsUnavailable = "migrations unavailable"
+	ReasonMigrationsPending     = "migrations pending"
+
+	// PingTimeout bounds the database ping of /readyz.
+	PingTimeout = 2 * time.Second
+)
+
+// NewChecker returns a Checker whose uptime counts from now and which logs the
+// errors its checks fail with to logger.
+func NewChecker(db *sql.DB, migrator *migrate.Migrator, version string, logger log.Logger) *Checker {
+	return &Checker{
+		startedAt: time.Now(),
+		logger:    logger,
+		db:        db,
+		migrator:  migrator,
+		version:   version,
+	}
+}
+
+// RegisterRoutes mounts /healthz, /readyz and /status on router.
+func (c *Checker) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/healthz", c.Healthz).Methods(http.MethodGet)
+	router.HandleFunc("/readyz", c.Readyz).Methods(http.MethodGet)
+	router.HandleFunc("/status", c.Status).Methods(http.MethodGet)
+}
+
+// SetDraining makes /readyz fail from now on, so that load balancers stop
+// sending requests while the server shuts down.
+func (c *Checker) SetDraining() {
+	c.draining.Store(true)
+}
+
+// Healthz reports that the process is alive and serving.
+func (c *Checker) Healthz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, StatusResponse{Status: StatusOK})
+}
+
+// Readyz reports whether the server is not draining, the database answers a
+// ping and every migration has been applied, with 503 Service Unavailable
+// otherwise.
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	ready := c.ready(r.Context())
+	if ready.Status != StatusReady {
+		writeJSON(w, http.StatusServiceUnavailable, ready)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ready)
+}
+
+// Status reports the build version, the uptime, the readiness and, when
+// there is a database, its pool stats and pending migrations.
+func (c *Checker) Status(w http.ResponseWriter, r *http.Request) {
+	details := DetailsResponse{
+		StartedAt: c.startedAt,
+		Version:   c.version,
+		Uptime:    time.Since(c.startedAt).Round(time.Second).String(),
+		Ready:     c.ready(r.Context()),
+	}
+
+	if c.db != nil {
+		stats := c.db.Stats()
+
+		details.DB = &DBStats{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDuration:       stats.WaitDuration.String(),
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		}
+	}
+
+	if c.migrator != nil {
+		details.Migrations = &Migrations{}
+
+		pending, err := c.migrator.Pending(r.Context())
+		if err != nil {
+			_ = c.logger.Log("check", "migrations", "err", err)
+			details.Migrations.Err = ReasonMigrationsUnavailable
+		}
+
+		details.Migrations.Pending = pending
+	}
+
+	writeJSON(w, http.StatusOK, details)
+}
+
+func (c *Checker) ready(ctx context.Context) StatusResponse {
+	if c.draining.Load() {
+		return StatusResponse{Status: StatusDraining}
+	}
+
+	if c.db != nil {
+		pingCtx, cancel := context.WithTimeout(ctx, PingTimeout)
+		defer cancel()
+
+		if err := c.db.PingContext(pingCtx); err != nil {
+			_ = c.logger.Log("check", "database", "err", err)
+
+			return StatusResponse{Status: StatusNotReady, Err: ReasonDatabaseUnavailable}
+		}
+	}
+
+	if c.migrator != nil {
+		pending, err := c.migrator.Pending(ctx)
+		if err != nil {
+			_ = c.logger.Log("check", "migrations", "err", err)
+
+			return StatusResponse{Status: StatusNotReady, Err: ReasonMigrationsUnavailable}
+		}
+
+		if pending > 0 {
+			return StatusResponse{Status: StatusNotReady, Err: ReasonMigrationsPending}
+		}
+	}
+
+	return StatusResponse{Status: StatusReady}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,171 @@
+package health_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"storage/internal/entity/mock"
+	"storage/internal/health"
+	"storage/internal/migrate"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	_ "modernc.org/sqlite"
+)
+
+// newChecker returns a checker logging to logger over an in-memory SQLite
+// database, which is fully migrated when migrated is true and has its last
+// migration pending otherwise.
+func newChecker(t *testing.T, migrated bool, logger log.Logger) (*health.Checker, *sql.DB) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	assert.Nil(t, err)
+
+	// Every connection to :memory: opens a database of its own.
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrate.New(db, "sqlite")
+	assert.Nil(t, err)
+
+	_, err = migrator.Up(context.TODO())
+	assert.Nil(t, err)
+
+	if !migrated {
+		_, err = migrator.Down(context.TODO())
+		assert.Nil(t, err)
+	}
+
+	return health.NewChecker(db, migrator, "v1.2.3", logger), db
+}
+
+func TestReadyz(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		outStatus string
+		outErr    string
+		// outLog is what the log tells about the failure beyond outErr.
+		outLog  string
+		outCode int
+	}{
+		{
+			name:      mock.NameNoError,
+			outCode:   http.StatusOK,
+			outStatus: health.StatusReady,
+		},
+		{
+			name:      "NoErrorWithoutDatabase",
+			outCode:   http.StatusOK,
+			outStatus: health.StatusReady,
+		},
+		{
+			name:      "ErrorPendingMigrations",
+			outCode:   http.StatusServiceUnavailable,
+			outStatus: health.StatusNotReady,
+			outErr:    health.ReasonMigrationsPending,
+		},
+		{
+			name:      "ErrorMigrationsUnavailable",
+			outCode:   http.StatusServiceUnavailable,
+			outStatus: health.StatusNotReady,
+			outErr:    health.ReasonMigrationsUnavailable,
+			outLog:    "no such table: schema_migrations",
+		},
+		{
+			name:      "ErrorDraining",
+			outCode:   http.StatusServiceUnavailable,
+			outStatus: health.StatusDraining,
+		},
+		{
+			name:      mock.NameErrorDBClosed,
+			outCode:   http.StatusServiceUnavailable,
+			outStatus: health.StatusNotReady,
+			outErr:    health.ReasonDatabaseUnavailable,
+			outLog:    mock.ErrDatabaseClosed,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			checker, db := newChecker(t, tt.name != "ErrorPendingMigrations", log.NewLogfmtLogger(&buf))
+
+			switch tt.name {
+			case "NoErrorWithoutDatabase":
+				checker = health.NewChecker(nil, nil, "v1.2.3", log.NewNopLogger())
+			case "ErrorDraining":
+				checker.SetDraining()
+			case "ErrorMigrationsUnavailable":
+				_, err := db.Exec("DROP TABLE schema_migrations")
+				assert.Nil(t, err)
+			case mock.NameErrorDBClosed:
+				db.Close()
+			}
+
+			router := mux.NewRouter()
+			checker.RegisterRoutes(router)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			raw := w.Body.String()
+
+			var body health.StatusResponse
+
+			err := json.NewDecoder(w.Body).Decode(&body)
+			assert.Nil(t, err)
+
+			assert.Equal(t, tt.outCode, w.Code)
+			assert.Equal(t, tt.outStatus, body.Status)
+			assert.Equal(t, tt.outErr, body.Err)
+
+			if tt.outLog != "" {
+				assert.NotContains(t, raw, tt.outLog)
+				assert.Contains(t, buf.String(), tt.outLog)
+			}
+		})
+	}
+}
+
+func TestHealthzAndStatus(t *testing.T) {
+	t.Parallel()
+
+	checker, _ := newChecker(t, true, log.NewNopLogger())
+
+	router := mux.NewRouter()
+	checker.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	var details health.DetailsResponse
+
+	err := json.NewDecoder(w.Body).Decode(&details)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "v1.2.3", details.Version)
+	assert.Equal(t, health.StatusReady, details.Ready.Status)
+	assert.NotEmpty(t, details.Uptime)
+	assert.Equal(t, 1, details.DB.MaxOpenConnections)
+	assert.Equal(t, &health.Migrations{Pending: 0}, details.Migrations)
+}
@@ -0,0 +1,61 @@
+// Package metrics builds the Prometheus instruments of the service. They are
+// registered in the default registry, which Handler serves together with the
+// Go runtime and process metrics.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"storage/internal/endpoint"
+
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes the name of every metric of the service.
+const Namespace = "storage"
+
+// NewEndpointMetrics registers the instruments of
+// endpoint.InstrumentingMiddleware. It panics when called twice.
+func NewEndpointMetrics() endpoint.Metrics {
+	return endpoint.Metrics{
+		Requests: kitprometheus.NewCounterFrom(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "endpoint",
+			Name:      "requests_total",
+			Help:      "Number of requests served by each endpoint.",
+		}, []string{"method", "outcome"}),
+		Errors: kitprometheus.NewCounterFrom(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "endpoint",
+			Name:      "errors_total",
+			Help:      "Number of requests that failed in each endpoint.",
+		}, []string{"method"}),
+		Latency: kitprometheus.NewHistogramFrom(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "endpoint",
+			Name:      "request_duration_seconds",
+			Help:      "Time spent serving requests in each endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "outcome"}),
+	}
+}
+
+// RegisterDBStats registers the gauges and counters of the sql.DBStats of db,
+// labeled with dbName.
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler serves the registered metrics on GET /metrics in the Prometheus
+// text format.
+func Handler() http.Handler {
+	router := mux.NewRouter()
+	router.Methods(http.MethodGet).Path("/metrics").Handler(promhttp.Handler())
+
+	return router
+}
@@ -0,0 +1,58 @@
+package metrics_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/metrics"
+
+	"github.com/stretchr/testify/assert"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	assert.Nil(t, err)
+
+	defer db.Close()
+
+	err = metrics.RegisterDBStats(db, "sqlite")
+	assert.Nil(t, err)
+
+	next := func(context.Context, any) (any, error) {
+		return entity.CountErrorResponse{}, nil
+	}
+
+	endpoints := endpoint.InstrumentEndpoints(endpoint.Endpoints{CountLegacyPasswords: next}, metrics.NewEndpointMetrics())
+
+	_, err = endpoints.CountLegacyPasswords(context.TODO(), nil)
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+
+	metrics.Handler().ServeHTTP(w, r)
+
+	body, err := io.ReadAll(w.Result().Body)
+	assert.Nil(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, metric := range []string{
+		`storage_endpoint_requests_total{method="CountLegacyPasswords",outcome="success"} 1`,
+		`storage_endpoint_request_duration_seconds_count{method="CountLegacyPasswords",outcome="success"} 1`,
+		`go_sql_open_connections{db_name="sqlite"} 0`,
+		"go_goroutines",
+	} {
+		assert.Contains(t, string(body), metric)
+	}
+}
@@ -0,0 +1,401 @@
+// Package migrate applies the versioned schema migrations embedded in the
+// binary. Migrations live in migrations/<driver> as pairs of
+// NNNN_name.up.sql and NNNN_name.down.sql files, and the applied versions are
+// recorded in the schema_migrations table.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration ...
+type Migration struct {
+	Name    string
+	Up      string
+	Down    string
+	Version int
+}
+
+// Status tells whether a migration has been applied.
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Migrator applies the migrations of a driver to a database.
+type Migrator struct {
+	db         *sql.DB
+	driver     driver
+	migrations []Migration
+}
+
+// driver holds what differs between the supported databases.
+type driver struct {
+	// lock serializes migrators across processes, using conn for every
+	// statement until unlock is called.
+	lock        func(ctx context.Context, conn *sql.Conn) (unlock func(), err error)
+	placeholder func(n int) string
+}
+
+// advisoryLockID identifies the Postgres advisory lock held while migrating.
+// It is an arbitrary constant shared by every replica.
+const advisoryLockID = 4_918_522_364_113_029
+
+var (
+	ErrUnknownDriver    = errors.New("unknown migration driver")
+	ErrInvalidMigration = errors.New("invalid migration")
+)
+
+//go:embed migrations
+var migrationsFS embed.FS
+
+var drivers = map[string]driver{
+	"postgres": {
+		lock:        lockPostgres,
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	},
+	// SQLite serializes writers by itself; every migration runs in a write
+	// transaction that checks again whether it has been applied.
+	"sqlite": {
+		lock:        func(context.Context, *sql.Conn) (func(), error) { return func() {}, nil },
+		placeholder: func(int) string { return "?" },
+	},
+}
+
+// New returns a Migrator with the embedded migrations of driverName, which
+// is postgres or sqlite.
+func New(db *sql.DB, driverName string) (*Migrator, error) {
+	d, ok := drivers[driverName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDriver, driverName)
+	}
+
+	migrations, err := load(migrationsFS, path.Join("migrations", driverName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db, driver: d, migrations: migrations}, nil
+}
+
+// Up applies every pending migration in order and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if versions[migration.Version] {
+				continue
+			}
+
+			var ok bool
+
+			ok, err = m.run(ctx, conn, migration, true)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				applied = append(applied, migration)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("error to migrate up: %w", err)
+	}
+
+	return applied, nil
+}
+
+// Down reverts the latest applied migration and returns it, or nil when no
+// migration is applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if !versions[migration.Version] {
+				continue
+			}
+
+			var ok bool
+
+			ok, err = m.run(ctx, conn, migration, false)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				reverted = &migration
+			}
+
+			return nil
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error to migrate down: %w", err)
+	}
+
+	return reverted, nil
+}
+
+// Status returns every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) (statuses []Status, err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error to get migration status: %w", err)
+	}
+	defer conn.Close()
+
+	versions, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("error to get migration status: %w", err)
+	}
+
+	for _, migration := range m.migrations {
+		statuses = append(statuses, Status{Migration: migration, Applied: versions[migration.Version]})
+	}
+
+	return statuses, nil
+}
+
+// Pending returns how many known migrations have not been applied. Unlike
+// Status it never writes, so it fails when schema_migrations does not exist.
+func (m *Migrator) Pending(ctx context.Context) (count int, err error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error to count pending migrations: %w", err)
+	}
+	defer conn.Close()
+
+	versions, err := readVersions(ctx, conn)
+	if err != nil {
+		return 0, fmt.Errorf("error to count pending migrations: %w", err)
+	}
+
+	for _, migration := range m.migrations {
+		if !versions[migration.Version] {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// withLock runs fn on a single connection while holding the driver lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	unlock, err := m.driver.lock(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return fn(conn)
+}
+
+// appliedVersions creates the schema_migrations table if needed and returns
+// the versions recorded in it.
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations(
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`)
+	if err != nil {
+		return nil, err
+	}
+
+	return readVersions(ctx, conn)
+}
+
+// readVersions returns the versions recorded in schema_migrations.
+func readVersions(ctx context.Context, conn *sql.Conn) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]bool)
+
+	for rows.Next() {
+		var version int
+
+		if err = rows.Scan(&version); err != nil {
+			return nil, err
+		}
+
+		versions[version] = true
+	}
+
+	return versions, rows.Err()
+}
+
+// run applies migration, or reverts it when up is false, and records it in
+// one transaction. It reports false when another migrator got there first.
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration Migration, up bool) (ok bool, err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var count int
+
+	err = tx.QueryRowContext(
+		ctx,
+		"SELECT COUNT(*) FROM schema_migrations WHERE version = "+m.driver.placeholder(1),
+		migration.Version,
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	if (count > 0) == up {
+		return false, nil
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, migration.Up)
+		if err == nil {
+			_, err = tx.ExecContext(
+				ctx,
+				fmt.Sprintf(
+					"INSERT INTO schema_migrations(version, name) VALUES (%s, %s)",
+					m.driver.placeholder(1),
+					m.driver.placeholder(2),
+				),
+				migration.Version,
+				migration.Name,
+			)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx, migration.Down)
+		if err == nil {
+			_, err = tx.ExecContext(
+				ctx,
+				"DELETE FROM schema_migrations WHERE version = "+m.driver.placeholder(1),
+				migration.Version,
+			)
+		}
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("%04d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	return true, tx.Commit()
+}
+
+// lockPostgres takes a session-level advisory lock, so conn must be used
+// for every statement until the lock is released.
+func lockPostgres(ctx context.Context, conn *sql.Conn) (unlock func(), err error) {
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		// The lock goes away with the session anyway if this fails.
+		_, _ = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockID)
+	}, nil
+}
+
+// load reads the migrations in dir of fsys and sorts them by version.
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error to load migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		base, direction, ok := cutDirection(entry.Name())
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, entry.Name())
+		}
+
+		versionText, name, ok := strings.Cut(base, "_")
+
+		version, err := strconv.Atoi(versionText)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMigration, entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error to load migrations: %w", err)
+		}
+
+		migration, found := byVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("%w: version %d has two names", ErrInvalidMigration, version)
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: version %d needs both up and down", ErrInvalidMigration, migration.Version)
+		}
+
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// cutDirection splits "0001_name.up.sql" into "0001_name" and "up".
+func cutDirection(fileName string) (base, direction string, ok bool) {
+	for _, direction = range []string{"up", "down"} {
+		suffix := "." + direction + ".sql"
+		if strings.HasSuffix(fileName, suffix) {
+			return strings.TrimSuffix(fileName, suffix), direction, true
+		}
+	}
+
+	return "", "", false
+}
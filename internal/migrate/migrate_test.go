@@ -0,0 +1,275 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"storage/internal/entity/mock"
+	"storage/internal/migrate"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	_ "modernc.org/sqlite"
+)
+
+func newSQLiteDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	assert.Nil(t, err)
+
+	// Every connection to :memory: opens a database of its own.
+	db.SetMaxOpenConns(1)
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name         string
+		inDriverName string
+		outErr       string
+	}{
+		{
+			name:         mock.NameNoError,
+			inDriverName: "postgres",
+			outErr:       "",
+		},
+		{
+			name:         "NoErrorSQLite",
+			inDriverName: "sqlite",
+			outErr:       "",
+		},
+		{
+			name:         "ErrorUnknownDriver",
+			inDriverName: "mysql",
+			outErr:       migrate.ErrUnknownDriver.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			_, err := migrate.New(nil, tt.inDriverName)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.outErr == "" {
+				assert.Empty(t, resultErr)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestUpDownStatus(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+
+	migrator, err := migrate.New(db, "sqlite")
+	assert.Nil(t, err)
+
+	// Pending does not create schema_migrations.
+	_, err = migrator.Pending(context.TODO())
+	assert.ErrorContains(t, err, "no such table")
+
+	statuses, err := migrator.Status(context.TODO())
+	assert.Nil(t, err)
+	assert.NotEmpty(t, statuses)
+
+	for _, status := range statuses {
+		assert.False(t, status.Applied)
+	}
+
+	applied, err := migrator.Up(context.TODO())
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(statuses))
+
+	_, err = db.Exec("INSERT INTO users(username, password, email) VALUES ('a', 'b', 'c')")
+	assert.Nil(t, err)
+
+	// A second run has nothing left to apply.
+	applied, err = migrator.Up(context.TODO())
+	assert.Nil(t, err)
+	assert.Empty(t, applied)
+
+	pending, err := migrator.Pending(context.TODO())
+	assert.Nil(t, err)
+	assert.Zero(t, pending)
+
+	statuses, err = migrator.Status(context.TODO())
+	assert.Nil(t, err)
+
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+	}
+
+	for i := len(statuses) - 1; i >= 0; i-- {
+		reverted, err := migrator.Down(context.TODO())
+		assert.Nil(t, err)
+		assert.Equal(t, statuses[i].Version, reverted.Version)
+	}
+
+	reverted, err := migrator.Down(context.TODO())
+	assert.Nil(t, err)
+	assert.Nil(t, reverted)
+
+	_, err = db.Exec("SELECT * FROM users")
+	assert.ErrorContains(t, err, "no such table")
+}
+
+// TestUpAdoptsInitSQL checks that a database created by the former
+// ias/init.sql, before there were migrations, is migrated keeping its users.
+func TestUpAdoptsInitSQL(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+
+	_, err := db.Exec(`CREATE TABLE users(
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username VARCHAR(64) NOT NULL UNIQUE,
+		password VARCHAR(64) NOT NULL,
+		email VARCHAR(64) NOT NULL UNIQUE
+	)`)
+	assert.Nil(t, err)
+
+	_, err = db.Exec("INSERT INTO users(username, password, email) VALUES (?, ?, ?)",
+		mock.UsernameTest, mock.LegacyHashTest, mock.EmailTest)
+	assert.Nil(t, err)
+
+	migrator, err := migrate.New(db, "sqlite")
+	assert.Nil(t, err)
+
+	statuses, err := migrator.Status(context.TODO())
+	assert.Nil(t, err)
+
+	applied, err := migrator.Up(context.TODO())
+	assert.Nil(t, err)
+	assert.Len(t, applied, len(statuses))
+
+	var username string
+
+	err = db.QueryRow("SELECT username FROM users WHERE password = ?", mock.LegacyHashTest).Scan(&username)
+	assert.Nil(t, err)
+	assert.Equal(t, mock.UsernameTest, username)
+}
+
+func TestUpPostgresAdvisoryLock(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		outErr string
+	}{
+		{
+			name:   mock.NameNoError,
+			outErr: "",
+		},
+		{
+			name:   "ErrorMigration",
+			outErr: "0001_create_users: " + sqlmock.ErrCancelled.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			migrator, err := migrate.New(db, "postgres")
+			assert.Nil(t, err)
+
+			dbMock.ExpectExec(`^SELECT pg_advisory_lock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+			dbMock.ExpectExec("^CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+			dbMock.ExpectQuery("^SELECT version FROM schema_migrations").
+				WillReturnRows(sqlmock.NewRows([]string{"version"}))
+			dbMock.ExpectBegin()
+			dbMock.ExpectQuery(`^SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+			if tt.name == mock.NameNoError {
+				dbMock.ExpectExec("^CREATE TABLE IF NOT EXISTS users").WillReturnResult(sqlmock.NewResult(0, 0))
+				dbMock.ExpectExec("^INSERT INTO schema_migrations").
+					WithArgs(1, "create_users").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery(`^SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				dbMock.ExpectExec("^CREATE TABLE login_attempts").WillReturnResult(sqlmock.NewResult(0, 0))
+				dbMock.ExpectExec("^INSERT INTO schema_migrations").
+					WithArgs(2, "create_login_attempts").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery(`^SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+					WithArgs(3).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				dbMock.ExpectExec("^CREATE TABLE api_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+				dbMock.ExpectExec("^INSERT INTO schema_migrations").
+					WithArgs(3, "create_api_keys").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery(`^SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+					WithArgs(4).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				dbMock.ExpectExec(`ALTER TABLE users ALTER COLUMN password TYPE VARCHAR\(255\)`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				dbMock.ExpectExec("^INSERT INTO schema_migrations").
+					WithArgs(4, "widen_users_password").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+				dbMock.ExpectBegin()
+				dbMock.ExpectQuery(`^SELECT COUNT\(\*\) FROM schema_migrations WHERE version = \$1`).
+					WithArgs(5).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				dbMock.ExpectExec("^ALTER TABLE login_attempts ADD COLUMN last_failure").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				dbMock.ExpectExec("^INSERT INTO schema_migrations").
+					WithArgs(5, "add_login_attempts_last_failure").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+			} else {
+				dbMock.ExpectExec("^CREATE TABLE IF NOT EXISTS users").WillReturnError(sqlmock.ErrCancelled)
+				dbMock.ExpectRollback()
+			}
+
+			dbMock.ExpectExec(`^SELECT pg_advisory_unlock\(\$1\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+			applied, err := migrator.Up(context.TODO())
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+				assert.Len(t, applied, 5)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+				assert.Empty(t, applied)
+			}
+
+			assert.Nil(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
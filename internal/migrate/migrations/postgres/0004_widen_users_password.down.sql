@@ -0,0 +1 @@
+-- 0001 creates the password column this wide, so it is left as it is.
@@ -0,0 +1,4 @@
+-- Databases created by the former ias/init.sql, which 0001 adopts as they
+-- are, hold passwords of at most 64 characters, too few for argon2id and
+-- bcrypt hashes.
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(255);
@@ -0,0 +1,5 @@
+CREATE TABLE login_attempts(
+    username TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    locked_until DATETIME
+);
@@ -0,0 +1,7 @@
+CREATE TABLE api_keys(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- SQLite does not enforce the length of VARCHAR columns, so the passwords of
+-- the databases 0001 adopts are wide enough already. This migration keeps the
+-- versions in step with Postgres.
@@ -0,0 +1 @@
+ALTER TABLE login_attempts ADD COLUMN last_failure DATETIME;
@@ -0,0 +1,151 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2idParams ...
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Argon2id hashes passwords with argon2id and encodes them in PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+type Argon2id struct {
+	params Argon2idParams
+}
+
+const argon2idPrefix = "$argon2id$"
+
+var (
+	ErrInvalidHash         = errors.New("invalid encoded hash")
+	ErrIncompatibleVersion = errors.New("incompatible argon2 version")
+)
+
+// DefaultArgon2idParams follows the OWASP recommendation for argon2id.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// NewArgon2id ...
+func NewArgon2id(params Argon2idParams) *Argon2id {
+	return &Argon2id{params: params}
+}
+
+// Hash ...
+func (a Argon2id) Hash(password string) (encodedHash string, err error) {
+	salt := make([]byte, a.params.SaltLength)
+
+	if _, err = rand.Read(salt); err != nil {
+		return "", fmt.Errorf("error to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey(
+		[]byte(password),
+		salt,
+		a.params.Iterations,
+		a.params.Memory,
+		a.params.Parallelism,
+		a.params.KeyLength,
+	)
+
+	encodedHash = fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		a.params.Memory,
+		a.params.Iterations,
+		a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return encodedHash, nil
+}
+
+// Verify ...
+func (a Argon2id) Verify(password, encodedHash string) (ok bool, err error) {
+	params, salt, key, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey := argon2.IDKey(
+		[]byte(password),
+		salt,
+		params.Iterations,
+		params.Memory,
+		params.Parallelism,
+		params.KeyLength,
+	)
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+// NeedsRehash reports whether encodedHash is not an argon2id hash.
+func (a Argon2id) NeedsRehash(encodedHash string) bool {
+	return !strings.HasPrefix(encodedHash, argon2idPrefix)
+}
+
+func decodeArgon2idHash(encodedHash string) (params Argon2idParams, salt, key []byte, err error) {
+	if !strings.HasPrefix(encodedHash, argon2idPrefix) {
+		return Argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	// "", "argon2id", "v=19", "m=65536,t=3,p=2", "<salt>", "<key>"
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 {
+		return Argon2idParams{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	if version != argon2.Version {
+		return Argon2idParams{}, nil, nil, ErrIncompatibleVersion
+	}
+
+	_, err = fmt.Sscanf(
+		parts[3],
+		"m=%d,t=%d,p=%d",
+		&params.Memory,
+		&params.Iterations,
+		&params.Parallelism,
+	)
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
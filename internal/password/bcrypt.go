@@ -0,0 +1,58 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptPrefixes are the prefixes of the bcrypt hash versions.
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// Bcrypt hashes passwords with bcrypt, whose output is already a
+// self-describing $2a$<cost>$<salt+key> string.
+type Bcrypt struct {
+	cost int
+}
+
+// NewBcrypt ...
+func NewBcrypt(cost int) *Bcrypt {
+	return &Bcrypt{cost: cost}
+}
+
+// Hash ...
+func (b Bcrypt) Hash(password string) (encodedHash string, err error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
+	if err != nil {
+		return "", fmt.Errorf("error to hash password: %w", err)
+	}
+
+	return string(hash), nil
+}
+
+// Verify ...
+func (b Bcrypt) Verify(password, encodedHash string) (ok bool, err error) {
+	err = bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	return true, nil
+}
+
+// NeedsRehash reports whether encodedHash is not a bcrypt hash.
+func (b Bcrypt) NeedsRehash(encodedHash string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(encodedHash, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
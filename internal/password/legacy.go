@@ -0,0 +1,29 @@
+package password
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+const legacyHashLength = sha256.Size * 2
+
+// IsLegacy reports whether encodedHash is an unsalted SHA-256 hex digest, the
+// format used before passwords were hashed with a Hasher.
+func IsLegacy(encodedHash string) bool {
+	if len(encodedHash) != legacyHashLength {
+		return false
+	}
+
+	_, err := hex.DecodeString(encodedHash)
+
+	return err == nil
+}
+
+// VerifyLegacy compares password against a legacy SHA-256 hex digest in
+// constant time.
+func VerifyLegacy(password, encodedHash string) bool {
+	hash := sha256.Sum256([]byte(password))
+
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(hash[:])), []byte(encodedHash)) == 1
+}
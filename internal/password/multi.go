@@ -0,0 +1,47 @@
+package password
+
+// Hasher hashes passwords with one algorithm and verifies hashes of it,
+// which NeedsRehash tells apart from those of other algorithms by their
+// prefix.
+type Hasher interface {
+	Hash(password string) (encodedHash string, err error)
+	Verify(password, encodedHash string) (ok bool, err error)
+	NeedsRehash(encodedHash string) bool
+}
+
+// Multi hashes passwords with its current Hasher and verifies every hash with
+// the Hasher of its algorithm, so that the hashes of a former hasher keep
+// working until they are rehashed.
+type Multi struct {
+	current Hasher
+	hashers []Hasher
+}
+
+// NewMulti returns a Multi that hashes with current and verifies the hashes
+// of current and others.
+func NewMulti(current Hasher, others ...Hasher) *Multi {
+	return &Multi{current: current, hashers: append([]Hasher{current}, others...)}
+}
+
+// Hash ...
+func (m Multi) Hash(password string) (encodedHash string, err error) {
+	return m.current.Hash(password)
+}
+
+// Verify verifies password against encodedHash with the Hasher of its
+// algorithm, and fails with ErrInvalidHash when none has it.
+func (m Multi) Verify(password, encodedHash string) (ok bool, err error) {
+	for _, hasher := range m.hashers {
+		if !hasher.NeedsRehash(encodedHash) {
+			return hasher.Verify(password, encodedHash)
+		}
+	}
+
+	return false, ErrInvalidHash
+}
+
+// NeedsRehash reports whether encodedHash is not of the algorithm of the
+// current Hasher.
+func (m Multi) NeedsRehash(encodedHash string) bool {
+	return m.current.NeedsRehash(encodedHash)
+}
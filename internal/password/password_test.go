@@ -0,0 +1,192 @@
+package password_test
+
+import (
+	"testing"
+
+	"storage/internal/entity/mock"
+	"storage/internal/password"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type hasher interface {
+	Hash(string) (string, error)
+	Verify(string, string) (bool, error)
+}
+
+func TestHashAndVerify(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		hasher     hasher
+		name       string
+		inPassword string
+		outOK      bool
+	}{
+		{
+			name:       "Argon2id" + mock.NameNoError,
+			hasher:     password.NewArgon2id(password.DefaultArgon2idParams),
+			inPassword: mock.PasswordTest,
+			outOK:      true,
+		},
+		{
+			name:       "Argon2idMismatch",
+			hasher:     password.NewArgon2id(password.DefaultArgon2idParams),
+			inPassword: "other",
+			outOK:      false,
+		},
+		{
+			name:       "Bcrypt" + mock.NameNoError,
+			hasher:     password.NewBcrypt(bcrypt.MinCost),
+			inPassword: mock.PasswordTest,
+			outOK:      true,
+		},
+		{
+			name:       "BcryptMismatch",
+			hasher:     password.NewBcrypt(bcrypt.MinCost),
+			inPassword: "other",
+			outOK:      false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			hash, err := tt.hasher.Hash(mock.PasswordTest)
+			assert.Nil(t, err)
+			assert.NotContains(t, hash, mock.PasswordTest)
+
+			ok, err := tt.hasher.Verify(tt.inPassword, hash)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.outOK, ok)
+		})
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		hasher hasher
+		name   string
+		inHash string
+	}{
+		{
+			name:   "Argon2idBadPrefix",
+			hasher: password.NewArgon2id(password.DefaultArgon2idParams),
+			inHash: mock.LegacyHashTest,
+		},
+		{
+			name:   "Argon2idBadParams",
+			hasher: password.NewArgon2id(password.DefaultArgon2idParams),
+			inHash: "$argon2id$v=19$m=x,t=3,p=2$c2FsdA$a2V5",
+		},
+		{
+			name:   "Argon2idBadVersion",
+			hasher: password.NewArgon2id(password.DefaultArgon2idParams),
+			inHash: "$argon2id$v=16$m=65536,t=3,p=2$c2FsdA$a2V5",
+		},
+		{
+			name:   "BcryptBadHash",
+			hasher: password.NewBcrypt(bcrypt.MinCost),
+			inHash: mock.LegacyHashTest,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ok, err := tt.hasher.Verify(mock.PasswordTest, tt.inHash)
+			assert.NotNil(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestMulti(t *testing.T) {
+	t.Parallel()
+
+	argon2id := password.NewArgon2id(password.DefaultArgon2idParams)
+	bcryptHasher := password.NewBcrypt(bcrypt.MinCost)
+
+	for _, tt := range []struct {
+		hasher         hasher
+		name           string
+		outNeedsRehash bool
+	}{
+		{name: "Current", hasher: argon2id, outNeedsRehash: false},
+		{name: "Other", hasher: bcryptHasher, outNeedsRehash: true},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			multi := password.NewMulti(argon2id, bcryptHasher)
+
+			hash, err := tt.hasher.Hash(mock.PasswordTest)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.outNeedsRehash, multi.NeedsRehash(hash))
+
+			ok, err := multi.Verify(mock.PasswordTest, hash)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+
+			ok, err = multi.Verify("other", hash)
+			assert.Nil(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestMultiInvalidHash(t *testing.T) {
+	t.Parallel()
+
+	multi := password.NewMulti(password.NewArgon2id(password.DefaultArgon2idParams))
+
+	ok, err := multi.Verify(mock.PasswordTest, "$2b$04$abc")
+	assert.ErrorIs(t, err, password.ErrInvalidHash)
+	assert.False(t, ok)
+}
+
+func TestLegacy(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name       string
+		inPassword string
+		inHash     string
+		outLegacy  bool
+		outOK      bool
+	}{
+		{
+			name:       mock.NameNoError,
+			inPassword: mock.PasswordTest,
+			inHash:     mock.LegacyHashTest,
+			outLegacy:  true,
+			outOK:      true,
+		},
+		{
+			name:       "Mismatch",
+			inPassword: "other",
+			inHash:     mock.LegacyHashTest,
+			outLegacy:  true,
+			outOK:      false,
+		},
+		{
+			name:       "NotLegacy",
+			inPassword: mock.PasswordTest,
+			inHash:     "$argon2id$v=19$m=65536,t=3,p=2$c2FsdA$a2V5",
+			outLegacy:  false,
+			outOK:      false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.outLegacy, password.IsLegacy(tt.inHash))
+			assert.Equal(t, tt.outOK, password.VerifyLegacy(tt.inPassword, tt.inHash))
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package ratelimit keeps a token bucket per client, keyed by its
+// authenticated API key or IP address, for the rate limiting middleware of
+// the endpoints.
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+	"golang.org/x/time/rate"
+)
+
+// Limiter holds a token bucket of the same Limit for every client.
+type Limiter struct {
+	clients   map[string]*client
+	lastSweep time.Time
+	limit     Limit
+	mu        sync.Mutex
+}
+
+// Limit lets a client make Rate requests per second, and up to Burst at once.
+type Limit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+// Error is returned for the requests over the limit. It matches
+// kitratelimit.ErrLimited.
+type Error struct {
+	// RetryAfter is how long the client has to wait for its next request to
+	// be allowed.
+	RetryAfter time.Duration
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// keyContextKey is the context key of the client key.
+type keyContextKey struct{}
+
+// sweepInterval is how often the clients idle long enough to have a full
+// bucket again are forgotten.
+const sweepInterval = time.Minute
+
+// New returns a Limiter of limit.
+func New(limit Limit) *Limiter {
+	return &Limiter{
+		clients:   make(map[string]*client),
+		lastSweep: time.Now(),
+		limit:     limit,
+	}
+}
+
+// Allow reports whether the client with the given key may make a request
+// now, taking a token from its bucket if so, or how long it has to wait
+// otherwise.
+func (l *Limiter) Allow(key string) (ok bool, retryAfter time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	c, found := l.clients[key]
+	if !found {
+		c = &client{limiter: rate.NewLimiter(l.limit.Rate, l.limit.Burst)}
+		l.clients[key] = c
+	}
+
+	c.lastSeen = now
+
+	reservation := c.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, 0
+	}
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// sweep forgets the clients whose bucket has filled up again since their
+// last request, which is the same as starting over. It must be called with
+// mu held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval || l.limit.Rate <= 0 {
+		return
+	}
+
+	l.lastSweep = now
+	refill := time.Duration(float64(l.limit.Burst) / float64(l.limit.Rate) * float64(time.Second))
+
+	for key, c := range l.clients {
+		if now.Sub(c.lastSeen) > refill {
+			delete(l.clients, key)
+		}
+	}
+}
+
+// Error ...
+func (e *Error) Error() string {
+	return kitratelimit.ErrLimited.Error()
+}
+
+// Unwrap ...
+func (e *Error) Unwrap() error {
+	return kitratelimit.ErrLimited
+}
+
+// ClientKey returns the key of a client by the IP address of addr, a
+// host:port or bare host address.
+func ClientKey(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	return "ip:" + addr
+}
+
+// ClientAddr returns the address of the client of a request that came from
+// remoteAddr with the given X-Forwarded-For values. The values are only
+// trusted when remoteAddr is in trusted, in which case the client is the
+// last address they list that is not, so that a client cannot pick its key
+// by sending the header itself.
+func ClientAddr(remoteAddr string, forwardedFor []string, trusted []netip.Prefix) string {
+	addr := remoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	if !isTrusted(addr, trusted) {
+		return addr
+	}
+
+	var hops []string
+	for _, value := range forwardedFor {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break
+		}
+
+		addr = hop
+
+		if !isTrusted(hop, trusted) {
+			break
+		}
+	}
+
+	return addr
+}
+
+// isTrusted reports whether the IP address addr is in trusted.
+func isTrusted(addr string, trusted []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+
+	for _, prefix := range trusted {
+		if prefix.Contains(ip.Unmap()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// APIKeyClientKey returns the key of a client by the name of the API key it
+// was authenticated by, never by the secret of the key.
+func APIKeyClientKey(name string) string {
+	return "key:" + name
+}
+
+// NewContext returns a copy of ctx holding the client key.
+func NewContext(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, key)
+}
+
+// FromContext returns the client key of ctx, or "" if it has none.
+func FromContext(ctx context.Context) string {
+	key, _ := ctx.Value(keyContextKey{}).(string)
+
+	return key
+}
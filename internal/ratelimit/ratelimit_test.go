@@ -0,0 +1,146 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"storage/internal/ratelimit"
+
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	t.Parallel()
+
+	// One request per minute, two at once.
+	limiter := ratelimit.New(ratelimit.Limit{Rate: rate.Every(time.Minute), Burst: 2})
+
+	for i := 0; i < 2; i++ {
+		ok, retryAfter := limiter.Allow("ip:192.0.2.1")
+		assert.True(t, ok)
+		assert.Zero(t, retryAfter)
+	}
+
+	ok, retryAfter := limiter.Allow("ip:192.0.2.1")
+	assert.False(t, ok)
+	assert.InDelta(t, time.Minute, retryAfter, float64(time.Second))
+
+	// A rejected request takes no token, so the wait does not grow.
+	_, retryAfterAgain := limiter.Allow("ip:192.0.2.1")
+	assert.LessOrEqual(t, retryAfterAgain, retryAfter)
+
+	// Every client has a bucket of its own.
+	ok, _ = limiter.Allow("ip:192.0.2.2")
+	assert.True(t, ok)
+}
+
+func TestClientKey(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		inAddr string
+		out    string
+	}{
+		{name: "IPv4", inAddr: "192.0.2.1:4242", out: "ip:192.0.2.1"},
+		{name: "IPv6", inAddr: "[2001:db8::1]:4242", out: "ip:2001:db8::1"},
+		{name: "NoPort", inAddr: "192.0.2.1", out: "ip:192.0.2.1"},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.out, ratelimit.ClientKey(tt.inAddr))
+		})
+	}
+}
+
+func TestClientAddr(t *testing.T) {
+	t.Parallel()
+
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	for _, tt := range []struct {
+		name           string
+		inRemoteAddr   string
+		inForwardedFor []string
+		out            string
+	}{
+		{name: "NoHeader", inRemoteAddr: "10.0.0.1:4242", out: "10.0.0.1"},
+		{
+			name:           "UntrustedProxy",
+			inRemoteAddr:   "192.0.2.1:4242",
+			inForwardedFor: []string{"198.51.100.1"},
+			out:            "192.0.2.1",
+		},
+		{
+			name:           "TrustedProxy",
+			inRemoteAddr:   "10.0.0.1:4242",
+			inForwardedFor: []string{"198.51.100.1"},
+			out:            "198.51.100.1",
+		},
+		{
+			name:           "MappedTrustedProxy",
+			inRemoteAddr:   "[::ffff:10.0.0.1]:4242",
+			inForwardedFor: []string{"198.51.100.1"},
+			out:            "198.51.100.1",
+		},
+		{
+			name:           "SpoofedHop",
+			inRemoteAddr:   "10.0.0.1:4242",
+			inForwardedFor: []string{"203.0.113.1, 198.51.100.1"},
+			out:            "198.51.100.1",
+		},
+		{
+			name:           "TrustedHops",
+			inRemoteAddr:   "10.0.0.1:4242",
+			inForwardedFor: []string{"198.51.100.1, 10.0.0.2", "10.0.0.3"},
+			out:            "198.51.100.1",
+		},
+		{
+			name:           "OnlyTrustedHops",
+			inRemoteAddr:   "10.0.0.1:4242",
+			inForwardedFor: []string{"10.0.0.2,10.0.0.3"},
+			out:            "10.0.0.2",
+		},
+		{
+			name:           "InvalidHop",
+			inRemoteAddr:   "10.0.0.1:4242",
+			inForwardedFor: []string{"198.51.100.1, unknown, 10.0.0.2"},
+			out:            "10.0.0.2",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.out, ratelimit.ClientAddr(tt.inRemoteAddr, tt.inForwardedFor, trusted))
+		})
+	}
+}
+
+func TestAPIKeyClientKey(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "key:writer", ratelimit.APIKeyClientKey("writer"))
+}
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, ratelimit.FromContext(context.TODO()))
+	assert.Equal(t, "ip:192.0.2.1", ratelimit.FromContext(ratelimit.NewContext(context.TODO(), "ip:192.0.2.1")))
+}
+
+func TestError(t *testing.T) {
+	t.Parallel()
+
+	var err error = &ratelimit.Error{RetryAfter: time.Second}
+
+	assert.ErrorIs(t, err, kitratelimit.ErrLimited)
+	assert.Equal(t, kitratelimit.ErrLimited.Error(), err.Error())
+}
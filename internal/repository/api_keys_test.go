@@ -0,0 +1,163 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPIKeys runs the same scenario on the memory and SQLite repositories.
+func TestAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		newRepo func(t *testing.T) auth.KeyRepository
+		name    string
+	}{
+		{
+			name:    "Memory",
+			newRepo: func(t *testing.T) auth.KeyRepository { return newMemory(t) },
+		},
+		{
+			name:    "SQLite",
+			newRepo: func(t *testing.T) auth.KeyRepository { return newSQLite(t) },
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := tt.newRepo(t)
+
+			keys, err := repo.ListAPIKeys(context.TODO())
+			assert.Nil(t, err)
+			assert.Empty(t, keys)
+
+			writer := entity.APIKey{
+				Name:   "writer",
+				Hash:   auth.Hash("sk_writer"),
+				Scopes: []string{auth.ScopeRead, auth.ScopeWrite},
+			}
+			reader := entity.APIKey{Name: "reader", Hash: auth.Hash("sk_reader"), Scopes: []string{auth.ScopeRead}}
+
+			for _, key := range []entity.APIKey{writer, reader} {
+				assert.Nil(t, repo.InsertAPIKey(context.TODO(), key))
+			}
+
+			err = repo.InsertAPIKey(context.TODO(), entity.APIKey{
+				Name:   "writer",
+				Hash:   auth.Hash("sk_other"),
+				Scopes: []string{auth.ScopeAdmin},
+			})
+			assert.ErrorIs(t, err, auth.ErrKeyNameTaken)
+
+			key, err := repo.GetAPIKeyByHash(context.TODO(), auth.Hash("sk_writer"))
+			assert.Nil(t, err)
+			assert.Equal(t, writer.Name, key.Name)
+			assert.Equal(t, writer.Scopes, key.Scopes)
+			assert.False(t, key.CreatedAt.IsZero())
+
+			_, err = repo.GetAPIKeyByHash(context.TODO(), auth.Hash("sk_other"))
+			assert.ErrorIs(t, err, auth.ErrKeyNotFound)
+
+			keys, err = repo.ListAPIKeys(context.TODO())
+			assert.Nil(t, err)
+
+			if assert.Len(t, keys, 2) {
+				assert.Equal(t, "reader", keys[0].Name)
+				assert.Equal(t, "writer", keys[1].Name)
+			}
+
+			rowsAffected, err := repo.DeleteAPIKey(context.TODO(), "writer")
+			assert.Nil(t, err)
+			assert.Equal(t, 1, rowsAffected)
+
+			rowsAffected, err = repo.DeleteAPIKey(context.TODO(), "writer")
+			assert.Nil(t, err)
+			assert.Zero(t, rowsAffected)
+
+			_, err = repo.GetAPIKeyByHash(context.TODO(), auth.Hash("sk_writer"))
+			assert.ErrorIs(t, err, auth.ErrKeyNotFound)
+		})
+	}
+}
+
+func TestPostgresInsertAPIKey(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		inDBErr error
+		name    string
+		outErr  string
+	}{
+		{
+			name:   mock.NameNoError,
+			outErr: "",
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			outErr: "sql: database is closed",
+		},
+		{
+			name:    "ErrorKeyNameTaken",
+			inDBErr: &pq.Error{Code: "23505", Constraint: "api_keys_name_key"},
+			outErr:  auth.ErrKeyNameTaken.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			insert := dbMock.ExpectExec(
+				`^INSERT INTO api_keys\(name, key_hash, scopes\) VALUES \(\$1,\$2,\$3\)`,
+			).WithArgs(
+				"writer",
+				auth.Hash("sk_writer"),
+				"read,write",
+			)
+
+			if tt.inDBErr != nil {
+				insert.WillReturnError(tt.inDBErr)
+			} else {
+				insert.WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			err = repo.InsertAPIKey(context.TODO(), entity.APIKey{
+				Name:   "writer",
+				Hash:   auth.Hash("sk_writer"),
+				Scopes: []string{auth.ScopeRead, auth.ScopeWrite},
+			})
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
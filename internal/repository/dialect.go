@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"storage/internal/auth"
+	"storage/internal/service"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect holds what differs between the databases SQL runs on. Queries are
+// written with ? placeholders and rebound to the dialect's own.
+type Dialect interface {
+	// Placeholder returns the placeholder of the n-th query argument,
+	// counting from 1.
+	Placeholder(n int) string
+	// UniqueViolation translates a unique-violation error into the domain
+	// error of the violated column.
+	UniqueViolation(err error) (domainErr error, ok bool)
+	// System returns the name of the database in traces, its OpenTelemetry
+	// db.system.
+	System() string
+}
+
+// PostgresDialect is the Dialect of Postgres through lib/pq.
+type PostgresDialect struct{}
+
+// SQLiteDialect is the Dialect of SQLite through modernc.org/sqlite.
+type SQLiteDialect struct{}
+
+const (
+	uniqueViolationCode = "23505"
+
+	usernameConstraint = "users_username_key"
+	emailConstraint    = "users_email_key"
+
+	usernameColumn = "users.username"
+	emailColumn    = "users.email"
+
+	apiKeyNameConstraint = "api_keys_name_key"
+	apiKeyNameColumn     = "api_keys.name"
+)
+
+// Placeholder ...
+func (PostgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// UniqueViolation ...
+func (PostgresDialect) UniqueViolation(err error) (domainErr error, ok bool) {
+	var pqErr *pq.Error
+
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolationCode {
+		return nil, false
+	}
+
+	switch pqErr.Constraint {
+	case usernameConstraint:
+		return service.ErrUsernameTaken, true
+	case emailConstraint:
+		return service.ErrEmailTaken, true
+	case apiKeyNameConstraint:
+		return auth.ErrKeyNameTaken, true
+	default:
+		return nil, false
+	}
+}
+
+// System ...
+func (PostgresDialect) System() string {
+	return "postgresql"
+}
+
+// Placeholder ...
+func (SQLiteDialect) Placeholder(int) string {
+	return "?"
+}
+
+// UniqueViolation recognizes the violated column from the error message, which
+// reads "UNIQUE constraint failed: users.username".
+func (SQLiteDialect) UniqueViolation(err error) (domainErr error, ok bool) {
+	var sqliteErr *sqlite.Error
+
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code() != sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return nil, false
+	}
+
+	switch {
+	case strings.Contains(sqliteErr.Error(), usernameColumn):
+		return service.ErrUsernameTaken, true
+	case strings.Contains(sqliteErr.Error(), emailColumn):
+		return service.ErrEmailTaken, true
+	case strings.Contains(sqliteErr.Error(), apiKeyNameColumn):
+		return auth.ErrKeyNameTaken, true
+	default:
+		return nil, false
+	}
+}
+
+// System ...
+func (SQLiteDialect) System() string {
+	return "sqlite"
+}
+
+// rebind replaces the ? placeholders of query with those of dialect.
+func rebind(dialect Dialect, query string) string {
+	var (
+		b strings.Builder
+		n int
+	)
+
+	for _, r := range query {
+		if r == '?' {
+			n++
+
+			b.WriteString(dialect.Placeholder(n))
+
+			continue
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
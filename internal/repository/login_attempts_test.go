@@ -0,0 +1,165 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/repository"
+	"storage/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoginAttempts runs the same scenario on the memory and SQLite
+// repositories, for a username with a user and for one without.
+func TestLoginAttempts(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		newRepo    func(t *testing.T) service.LoginAttemptRepository
+		name       string
+		inUsername string
+	}{
+		{
+			name:       "Memory",
+			newRepo:    func(t *testing.T) service.LoginAttemptRepository { return newMemory(t) },
+			inUsername: "carol",
+		},
+		{
+			name:       "SQLite",
+			newRepo:    func(t *testing.T) service.LoginAttemptRepository { return newSQLite(t) },
+			inUsername: "carol",
+		},
+		{
+			name:       "SQLiteWithoutUser",
+			newRepo:    func(t *testing.T) service.LoginAttemptRepository { return newSQLite(t) },
+			inUsername: "nobody",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := tt.newRepo(t)
+
+			attempts, err := repo.GetLoginAttempts(context.TODO(), tt.inUsername)
+			assert.Nil(t, err)
+			assert.Equal(t, entity.LoginAttempts{}, attempts)
+
+			now := time.Now().Truncate(time.Millisecond)
+			locked := entity.LoginAttempts{LockedUntil: now.Add(time.Hour), LastFailure: now, Failures: 2}
+
+			for _, step := range []struct {
+				next           entity.LoginAttempts
+				inSeenFailures int
+				outRecorded    bool
+			}{
+				{next: entity.LoginAttempts{LastFailure: now, Failures: 1}, inSeenFailures: 0, outRecorded: true},
+				{next: locked, inSeenFailures: 1, outRecorded: true},
+				// A check that saw the attempts before the last failure
+				// does not record its own over it.
+				{next: entity.LoginAttempts{LastFailure: now, Failures: 2}, inSeenFailures: 1, outRecorded: false},
+			} {
+				recorded, recordErr := repo.RecordLoginFailure(
+					context.TODO(),
+					tt.inUsername,
+					step.inSeenFailures,
+					step.next,
+				)
+				assert.Nil(t, recordErr)
+				assert.Equal(t, step.outRecorded, recorded)
+			}
+
+			attempts, err = repo.GetLoginAttempts(context.TODO(), tt.inUsername)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, attempts.Failures)
+			assert.WithinDuration(t, locked.LockedUntil, attempts.LockedUntil, time.Millisecond)
+			assert.WithinDuration(t, now, attempts.LastFailure, time.Millisecond)
+
+			// Other usernames are not affected.
+			attempts, err = repo.GetLoginAttempts(context.TODO(), "bob")
+			assert.Nil(t, err)
+			assert.Equal(t, entity.LoginAttempts{}, attempts)
+
+			err = repo.ResetLoginAttempts(context.TODO(), tt.inUsername)
+			assert.Nil(t, err)
+
+			attempts, err = repo.GetLoginAttempts(context.TODO(), tt.inUsername)
+			assert.Nil(t, err)
+			assert.Equal(t, entity.LoginAttempts{}, attempts)
+		})
+	}
+}
+
+func TestPostgresRecordLoginFailure(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name            string
+		outErr          string
+		outRowsAffected int64
+		outRecorded     bool
+	}{
+		{
+			name:            mock.NameNoError,
+			outRowsAffected: 1,
+			outRecorded:     true,
+			outErr:          "",
+		},
+		{
+			name:            "Concurrent",
+			outRowsAffected: 0,
+			outRecorded:     false,
+			outErr:          "",
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			outErr: "sql: database is closed",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			next := entity.LoginAttempts{LastFailure: time.Now(), Failures: 3}
+
+			dbMock.ExpectExec(`^INSERT INTO login_attempts\(username, failures, locked_until, last_failure\) `+
+				`VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT \(username\) DO UPDATE SET failures = excluded.failures, `+
+				`locked_until = excluded.locked_until, last_failure = excluded.last_failure `+
+				`WHERE login_attempts.failures = \$5`).
+				WithArgs(mock.UsernameTest, next.Failures, sql.NullTime{}, next.LastFailure.UTC(), 2).
+				WillReturnResult(sqlmock.NewResult(0, tt.outRowsAffected))
+
+			recorded, err := repo.RecordLoginFailure(context.TODO(), mock.UsernameTest, 2, next)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.outErr == "" {
+				assert.Empty(t, resultErr)
+				assert.Equal(t, tt.outRecorded, recorded)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
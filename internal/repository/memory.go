@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/password"
+	"storage/internal/service"
+)
+
+// Memory is a service.UserRepository, service.LoginAttemptRepository and
+// auth.KeyRepository that keeps users, login attempts and API keys in memory.
+// It enforces the same uniqueness rules as the database and is safe for
+// concurrent use. It is meant for local development and tests.
+type Memory struct {
+	users    map[int]entity.User
+	attempts map[string]entity.LoginAttempts
+	keys     map[string]entity.APIKey
+	lastID   int
+	mu       sync.RWMutex
+}
+
+// sortKeys maps every sort field to the value it sorts by, mirroring the
+// columns accepted by the SQL repository.
+var sortKeys = map[string]func(entity.User) string{
+	"id":       func(entity.User) string { return "" },
+	"username": func(user entity.User) string { return user.Username },
+	"email":    func(user entity.User) string { return user.Email },
+}
+
+// NewMemory ...
+func NewMemory() *Memory {
+	return &Memory{
+		users:    make(map[int]entity.User),
+		attempts: make(map[string]entity.LoginAttempts),
+		keys:     make(map[string]entity.APIKey),
+	}
+}
+
+// ListUsers ...
+func (m *Memory) ListUsers(_ context.Context, query service.ListUsersQuery) ([]entity.User, error) {
+	key, ok := sortKeys[query.SortBy]
+	if !ok {
+		return nil, unknownSortFieldError(query.SortBy)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	users := make([]entity.User, 0, len(m.users))
+
+	for _, user := range m.users {
+		if query.UsernamePrefix != "" && !strings.HasPrefix(user.Username, query.UsernamePrefix) {
+			continue
+		}
+
+		if query.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(user.Email), "@"+query.EmailDomain) {
+			continue
+		}
+
+		if query.HasCursor && !after(key(user), user.ID, query) {
+			continue
+		}
+
+		users = append(users, entity.User{ID: user.ID, Username: user.Username, Email: user.Email})
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		less := compare(key(users[i]), users[i].ID, key(users[j]), users[j].ID) < 0
+		if query.Descending {
+			return !less
+		}
+
+		return less
+	})
+
+	if len(users) > query.Limit {
+		users = users[:query.Limit]
+	}
+
+	return users, nil
+}
+
+// GetUserByID ...
+func (m *Memory) GetUserByID(_ context.Context, id int) (entity.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return entity.User{}, service.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// GetUserByUsername ...
+func (m *Memory) GetUserByUsername(_ context.Context, username string) (entity.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+
+	return entity.User{}, service.ErrUserNotFound
+}
+
+// InsertUser ...
+func (m *Memory) InsertUser(_ context.Context, user entity.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkUnique(0, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	m.lastID++
+	user.ID = m.lastID
+	m.users[user.ID] = user
+
+	return nil
+}
+
+// UpdateUser ...
+func (m *Memory) UpdateUser(_ context.Context, id int, patch entity.UserPatch) (entity.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok {
+		return entity.User{}, service.ErrUserNotFound
+	}
+
+	if patch.Username != nil {
+		user.Username = *patch.Username
+	}
+
+	if patch.Email != nil {
+		user.Email = *patch.Email
+	}
+
+	if patch.Password != nil {
+		user.Password = *patch.Password
+	}
+
+	if err := m.checkUnique(id, user.Username, user.Email); err != nil {
+		return entity.User{}, err
+	}
+
+	m.users[id] = user
+
+	return user, nil
+}
+
+// DeleteUser ...
+func (m *Memory) DeleteUser(_ context.Context, id int) (rowsAffected int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[id]; !ok {
+		return 0, nil
+	}
+
+	delete(m.users, id)
+
+	return 1, nil
+}
+
+// CountLegacyPasswords ...
+func (m *Memory) CountLegacyPasswords(_ context.Context) (count int, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, user := range m.users {
+		if password.IsLegacy(user.Password) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// UpgradePassword ...
+func (m *Memory) UpgradePassword(_ context.Context, id int, oldHash, newHash string) (upgraded bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, ok := m.users[id]
+	if !ok || user.Password != oldHash {
+		return false, nil
+	}
+
+	user.Password = newHash
+	m.users[id] = user
+
+	return true, nil
+}
+
+// GetLoginAttempts ...
+func (m *Memory) GetLoginAttempts(_ context.Context, username string) (entity.LoginAttempts, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.attempts[username], nil
+}
+
+// RecordLoginFailure ...
+func (m *Memory) RecordLoginFailure(
+	_ context.Context,
+	username string,
+	seenFailures int,
+	next entity.LoginAttempts,
+) (recorded bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.attempts[username].Failures != seenFailures {
+		return false, nil
+	}
+
+	m.attempts[username] = next
+
+	return true, nil
+}
+
+// ResetLoginAttempts ...
+func (m *Memory) ResetLoginAttempts(_ context.Context, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, username)
+
+	return nil
+}
+
+// InsertAPIKey ...
+func (m *Memory) InsertAPIKey(_ context.Context, key entity.APIKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[key.Name]; ok {
+		return auth.ErrKeyNameTaken
+	}
+
+	key.Scopes = append([]string(nil), key.Scopes...)
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+
+	m.keys[key.Name] = key
+
+	return nil
+}
+
+// GetAPIKeyByHash ...
+func (m *Memory) GetAPIKeyByHash(_ context.Context, hash string) (entity.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.Hash == hash {
+			return key, nil
+		}
+	}
+
+	return entity.APIKey{}, auth.ErrKeyNotFound
+}
+
+// ListAPIKeys returns every API key by name.
+func (m *Memory) ListAPIKeys(_ context.Context) ([]entity.APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]entity.APIKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+
+	return keys, nil
+}
+
+// DeleteAPIKey ...
+func (m *Memory) DeleteAPIKey(_ context.Context, name string) (rowsAffected int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.keys[name]; !ok {
+		return 0, nil
+	}
+
+	delete(m.keys, name)
+
+	return 1, nil
+}
+
+// checkUnique reports whether username or email already belong to a user
+// other than the one with the given ID. It must be called with mu held.
+func (m *Memory) checkUnique(id int, username, email string) error {
+	for _, user := range m.users {
+		if user.ID == id {
+			continue
+		}
+
+		if user.Username == username {
+			return service.ErrUsernameTaken
+		}
+
+		if user.Email == email {
+			return service.ErrEmailTaken
+		}
+	}
+
+	return nil
+}
+
+// compare orders users by sort value and then by ID, like ORDER BY col, id.
+func compare(valueA string, idA int, valueB string, idB int) int {
+	if c := strings.Compare(valueA, valueB); c != 0 {
+		return c
+	}
+
+	switch {
+	case idA < idB:
+		return -1
+	case idA > idB:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// after reports whether the (value, id) position comes after the cursor of
+// query in its sort direction.
+func after(value string, id int, query service.ListUsersQuery) bool {
+	c := compare(value, id, query.AfterValue, query.AfterID)
+	if query.Descending {
+		return c < 0
+	}
+
+	return c > 0
+}
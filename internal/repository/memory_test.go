@@ -0,0 +1,257 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/repository"
+	"storage/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hashTest stands for a password hashed in a current, $-prefixed format.
+const hashTest = "$2a$04$hash"
+
+// newMemory returns a repository holding the users carol (ID 1), alice (ID 2)
+// and bob (ID 3), with emails at example.com except bob's.
+func newMemory(t *testing.T) *repository.Memory {
+	t.Helper()
+
+	repo := repository.NewMemory()
+
+	for _, user := range []entity.User{
+		{Username: "carol", Password: hashTest, Email: "carol@example.com"},
+		{Username: "alice", Password: mock.LegacyHashTest, Email: "alice@Example.com"},
+		{Username: "bob", Password: hashTest, Email: "bob@other.com"},
+	} {
+		err := repo.InsertUser(context.TODO(), user)
+		assert.Nil(t, err)
+	}
+
+	return repo
+}
+
+func TestMemoryListUsers(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name         string
+		outErr       string
+		inQuery      service.ListUsersQuery
+		outUsernames []string
+	}{
+		{
+			name:         mock.NameNoError,
+			inQuery:      service.ListUsersQuery{SortBy: "id", Limit: 10},
+			outUsernames: []string{"carol", "alice", "bob"},
+		},
+		{
+			name:         "NoErrorSortByUsernameDesc",
+			inQuery:      service.ListUsersQuery{SortBy: "username", Descending: true, Limit: 2},
+			outUsernames: []string{"carol", "bob"},
+		},
+		{
+			name: "NoErrorCursor",
+			inQuery: service.ListUsersQuery{
+				SortBy:     "username",
+				AfterValue: "alice",
+				AfterID:    2,
+				HasCursor:  true,
+				Limit:      10,
+			},
+			outUsernames: []string{"bob", "carol"},
+		},
+		{
+			name:         "NoErrorFilters",
+			inQuery:      service.ListUsersQuery{SortBy: "email", EmailDomain: "example.com", Limit: 10},
+			outUsernames: []string{"alice", "carol"},
+		},
+		{
+			name:         "NoErrorUsernamePrefix",
+			inQuery:      service.ListUsersQuery{SortBy: "id", UsernamePrefix: "b", Limit: 10},
+			outUsernames: []string{"bob"},
+		},
+		{
+			name:    "ErrorSortField",
+			inQuery: service.ListUsersQuery{SortBy: "password", Limit: 10},
+			outErr:  service.ErrInvalidListOptions.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			users, err := newMemory(t).ListUsers(context.TODO(), tt.inQuery)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.outErr == "" {
+				assert.Empty(t, resultErr)
+
+				usernames := make([]string, 0, len(users))
+				for _, user := range users {
+					assert.Empty(t, user.Password)
+
+					usernames = append(usernames, user.Username)
+				}
+
+				assert.Equal(t, tt.outUsernames, usernames)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestMemoryInsertUser(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		outErr string
+		inUser entity.User
+	}{
+		{
+			name:   mock.NameNoError,
+			inUser: entity.User{Username: mock.UsernameTest, Email: mock.EmailTest},
+			outErr: "",
+		},
+		{
+			name:   "ErrorUsernameTaken",
+			inUser: entity.User{Username: "bob", Email: mock.EmailTest},
+			outErr: service.ErrUsernameTaken.Error(),
+		},
+		{
+			name:   "ErrorEmailTaken",
+			inUser: entity.User{Username: mock.UsernameTest, Email: "bob@other.com"},
+			outErr: service.ErrEmailTaken.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			repo := newMemory(t)
+
+			err := repo.InsertUser(context.TODO(), tt.inUser)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+
+				user, getErr := repo.GetUserByUsername(context.TODO(), tt.inUser.Username)
+				assert.Nil(t, getErr)
+				assert.Equal(t, 4, user.ID)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestMemoryUpdateUser(t *testing.T) {
+	t.Parallel()
+
+	username, email := mock.UsernameTest, "bob@other.com"
+
+	for _, tt := range []struct {
+		inPatch entity.UserPatch
+		name    string
+		outErr  string
+		inID    int
+	}{
+		{
+			name:    mock.NameNoError,
+			inID:    1,
+			inPatch: entity.UserPatch{Username: &username},
+			outErr:  "",
+		},
+		{
+			name:    mock.NameErrorNoRows,
+			inID:    9,
+			inPatch: entity.UserPatch{Username: &username},
+			outErr:  service.ErrUserNotFound.Error(),
+		},
+		{
+			name:    "ErrorEmailTaken",
+			inID:    1,
+			inPatch: entity.UserPatch{Username: &username, Email: &email},
+			outErr:  service.ErrEmailTaken.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			repo := newMemory(t)
+
+			user, err := repo.UpdateUser(context.TODO(), tt.inID, tt.inPatch)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+				assert.Equal(t, mock.UsernameTest, user.Username)
+				assert.Equal(t, "carol@example.com", user.Email)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+
+				// A failed update must leave the user untouched.
+				_, err = repo.GetUserByUsername(context.TODO(), mock.UsernameTest)
+				assert.ErrorIs(t, err, service.ErrUserNotFound)
+			}
+		})
+	}
+}
+
+func TestMemoryDeleteUser(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name            string
+		inID            int
+		outRowsAffected int
+		outCount        int
+	}{
+		{
+			name:            mock.NameNoError,
+			inID:            2,
+			outRowsAffected: 1,
+			outCount:        0,
+		},
+		{
+			name:            mock.NameErrorNoRows,
+			inID:            9,
+			outRowsAffected: 0,
+			outCount:        1,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newMemory(t)
+
+			rowsAffected, err := repo.DeleteUser(context.TODO(), tt.inID)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.outRowsAffected, rowsAffected)
+
+			count, err := repo.CountLegacyPasswords(context.TODO())
+			assert.Nil(t, err)
+			assert.Equal(t, tt.outCount, count)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLegacyPasswords runs the same scenario on the memory and SQLite
+// repositories, which hold alice with mock.LegacyHashTest as user 2.
+func TestLegacyPasswords(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		newRepo func(t *testing.T) service.UserRepository
+		name    string
+	}{
+		{
+			name:    "Memory",
+			newRepo: func(t *testing.T) service.UserRepository { return newMemory(t) },
+		},
+		{
+			name:    "SQLite",
+			newRepo: func(t *testing.T) service.UserRepository { return newSQLite(t) },
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := tt.newRepo(t)
+
+			// Only the upper case digest is legacy, as password.IsLegacy says.
+			for i, passwordHashed := range []string{
+				strings.ToUpper(mock.LegacyHashTest),
+				"plain",
+				strings.Repeat("z", len(mock.LegacyHashTest)),
+				mock.LegacyHashTest[1:],
+			} {
+				err := repo.InsertUser(context.TODO(), entity.User{
+					Username: "legacy" + string(rune('a'+i)),
+					Password: passwordHashed,
+					Email:    "legacy" + string(rune('a'+i)) + "@example.com",
+				})
+				assert.Nil(t, err)
+			}
+
+			count, err := repo.CountLegacyPasswords(context.TODO())
+			assert.Nil(t, err)
+			assert.Equal(t, 2, count)
+
+			// A password changed since it was read is not upgraded.
+			upgraded, err := repo.UpgradePassword(context.TODO(), 2, "changed", hashTest)
+			assert.Nil(t, err)
+			assert.False(t, upgraded)
+
+			upgraded, err = repo.UpgradePassword(context.TODO(), 2, mock.LegacyHashTest, hashTest)
+			assert.Nil(t, err)
+			assert.True(t, upgraded)
+
+			user, err := repo.GetUserByID(context.TODO(), 2)
+			assert.Nil(t, err)
+			assert.Equal(t, hashTest, user.Password)
+
+			count, err = repo.CountLegacyPasswords(context.TODO())
+			assert.Nil(t, err)
+			assert.Equal(t, 1, count)
+		})
+	}
+}
@@ -0,0 +1,669 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/repository"
+	"storage/internal/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresListUsers(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name                         string
+		outID, outUsername, outEmail any
+		outErr                       string
+	}{
+		{
+			name:        mock.NameNoError,
+			outID:       mock.IDTest,
+			outUsername: mock.UsernameTest,
+			outEmail:    mock.EmailTest,
+			outErr:      "",
+		},
+		{
+			name:        mock.NameErrorDBClosed,
+			outID:       mock.IDTest,
+			outUsername: mock.UsernameTest,
+			outEmail:    mock.EmailTest,
+			outErr:      "sql: database is closed",
+		},
+		{
+			name:        "ErrorScanRows",
+			outID:       "id",
+			outUsername: 1,
+			outEmail:    1,
+			outErr:      "Scan error on column index 0",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			rows := sqlmock.NewRows(
+				[]string{
+					"id",
+					"username",
+					"email",
+				}).AddRow(
+				tt.outID,
+				tt.outUsername,
+				tt.outEmail,
+			)
+
+			dbMock.ExpectQuery("SELECT id, username, email FROM users").WillReturnRows(rows)
+
+			_, err = repo.ListUsers(context.TODO(), service.ListUsersQuery{SortBy: "id", Limit: 1})
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestPostgresListUsersQuery(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		outQuery string
+		outErr   string
+		outArgs  []driver.Value
+		inQuery  service.ListUsersQuery
+	}{
+		{
+			name:     mock.NameNoError,
+			inQuery:  service.ListUsersQuery{SortBy: "id", Limit: 3},
+			outQuery: `^SELECT id, username, email FROM users ORDER BY id ASC LIMIT \$1$`,
+			outArgs:  []driver.Value{3},
+		},
+		{
+			name: "NoErrorCursorByID",
+			inQuery: service.ListUsersQuery{
+				SortBy:    "id",
+				HasCursor: true,
+				AfterID:   mock.IDTest,
+				Limit:     3,
+			},
+			outQuery: `^SELECT id, username, email FROM users WHERE id > \$1 ORDER BY id ASC LIMIT \$2$`,
+			outArgs:  []driver.Value{mock.IDTest, 3},
+		},
+		{
+			name: "NoErrorFiltersAndCursor",
+			inQuery: service.ListUsersQuery{
+				SortBy:         "username",
+				Descending:     true,
+				UsernamePrefix: "user_",
+				EmailDomain:    "email.com",
+				HasCursor:      true,
+				AfterValue:     mock.UsernameTest,
+				AfterID:        mock.IDTest,
+				Limit:          3,
+			},
+			outQuery: `^SELECT id, username, email FROM users ` +
+				`WHERE username LIKE \$1 ESCAPE '\\' AND LOWER\(email\) LIKE \$2 ESCAPE '\\' ` +
+				`AND \(username, id\) < \(\$3, \$4\) ORDER BY username DESC, id DESC LIMIT \$5$`,
+			outArgs: []driver.Value{`user\_%`, "%@email.com", mock.UsernameTest, mock.IDTest, 3},
+		},
+		{
+			name:    "ErrorSortField",
+			inQuery: service.ListUsersQuery{SortBy: "password; --", Limit: 3},
+			outErr:  service.ErrInvalidListOptions.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			repo := repository.NewPostgres(db)
+
+			if tt.outQuery != "" {
+				dbMock.ExpectQuery(tt.outQuery).WithArgs(tt.outArgs...).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email"}))
+			}
+
+			_, err = repo.ListUsers(context.TODO(), tt.inQuery)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.outErr == "" {
+				assert.Empty(t, resultErr)
+				assert.Nil(t, dbMock.ExpectationsWereMet())
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestPostgresGetUserByID(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name                               string
+		outUsername, outPassword, outEmail string
+		outErr                             string
+		inID                               int
+	}{
+		{
+			name:        mock.NameNoError,
+			inID:        mock.IDTest,
+			outUsername: mock.UsernameTest,
+			outPassword: mock.PasswordTest,
+			outEmail:    mock.EmailTest,
+			outErr:      "",
+		},
+		{
+			name:        mock.NameErrorNoRows,
+			inID:        mock.IDTest,
+			outUsername: mock.UsernameTest,
+			outPassword: mock.PasswordTest,
+			outEmail:    mock.EmailTest,
+			outErr:      service.ErrUserNotFound.Error(),
+		},
+		{
+			name:        mock.NameErrorDBClosed,
+			inID:        mock.IDTest,
+			outUsername: mock.UsernameTest,
+			outPassword: mock.PasswordTest,
+			outEmail:    mock.EmailTest,
+			outErr:      "sql: database is closed",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			rows := sqlmock.NewRows(
+				[]string{
+					"id",
+					"username",
+					"password",
+					"email",
+				}).AddRow(
+				tt.inID,
+				tt.outUsername,
+				tt.outPassword,
+				tt.outEmail,
+			)
+
+			if tt.name == mock.NameErrorNoRows {
+				rows = sqlmock.NewRows([]string{"id", "username", "password", "email"})
+			}
+
+			dbMock.ExpectQuery(
+				"^SELECT id, username, password, email FROM users",
+			).WithArgs(tt.inID).WillReturnRows(rows)
+
+			_, err = repo.GetUserByID(context.TODO(), tt.inID)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestPostgresGetUserByUsername(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name       string
+		inUsername string
+		outErr     string
+	}{
+		{
+			name:       mock.NameNoError,
+			inUsername: mock.UsernameTest,
+			outErr:     "",
+		},
+		{
+			name:       mock.NameErrorNoRows,
+			inUsername: mock.UsernameTest,
+			outErr:     service.ErrUserNotFound.Error(),
+		},
+		{
+			name:       mock.NameErrorDBClosed,
+			inUsername: mock.UsernameTest,
+			outErr:     "sql: database is closed",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			rows := sqlmock.NewRows([]string{"id", "username", "password", "email"}).
+				AddRow(mock.IDTest, tt.inUsername, mock.PasswordTest, mock.EmailTest)
+
+			if tt.name == mock.NameErrorNoRows {
+				rows = sqlmock.NewRows([]string{"id", "username", "password", "email"})
+			}
+
+			dbMock.ExpectQuery(
+				"^SELECT id, username, password, email FROM users",
+			).WithArgs(tt.inUsername).WillReturnRows(rows)
+
+			user, err := repo.GetUserByUsername(context.TODO(), tt.inUsername)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+				assert.Equal(t, mock.IDTest, user.ID)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestPostgresInsertUser(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		inDBErr error
+		name    string
+		outErr  string
+	}{
+		{
+			name:   mock.NameNoError,
+			outErr: "",
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			outErr: "sql: database is closed",
+		},
+		{
+			name:    "ErrorUsernameTaken",
+			inDBErr: &pq.Error{Code: "23505", Constraint: "users_username_key"},
+			outErr:  service.ErrUsernameTaken.Error(),
+		},
+		{
+			name:    "ErrorEmailTaken",
+			inDBErr: &pq.Error{Code: "23505", Constraint: "users_email_key"},
+			outErr:  service.ErrEmailTaken.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			insert := dbMock.ExpectExec(
+				"^INSERT INTO users",
+			).WithArgs(
+				mock.UsernameTest,
+				mock.LegacyHashTest,
+				mock.EmailTest,
+			)
+
+			if tt.inDBErr != nil {
+				insert.WillReturnError(tt.inDBErr)
+			} else {
+				insert.WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			err = repo.InsertUser(context.TODO(), entity.User{
+				Username: mock.UsernameTest,
+				Password: mock.LegacyHashTest,
+				Email:    mock.EmailTest,
+			})
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestPostgresUpdateUser(t *testing.T) {
+	t.Parallel()
+
+	username, email, passwordHashed := mock.UsernameTest, mock.EmailTest, mock.LegacyHashTest
+
+	for _, tt := range []struct {
+		inDBErr  error
+		inPatch  entity.UserPatch
+		name     string
+		outQuery string
+		outErr   string
+		outArgs  []driver.Value
+	}{
+		{
+			name:     mock.NameNoError,
+			inPatch:  entity.UserPatch{Username: &username, Password: &passwordHashed},
+			outQuery: `^UPDATE users SET username = \$1, password = \$2 WHERE id = \$3 RETURNING`,
+			outArgs:  []driver.Value{username, passwordHashed, mock.IDTest},
+			outErr:   "",
+		},
+		{
+			name:     mock.NameErrorNoRows,
+			inPatch:  entity.UserPatch{Email: &email},
+			outQuery: `^UPDATE users SET email = \$1 WHERE id = \$2 RETURNING`,
+			outArgs:  []driver.Value{email, mock.IDTest},
+			outErr:   service.ErrUserNotFound.Error(),
+		},
+		{
+			name:     "ErrorEmailTaken",
+			inPatch:  entity.UserPatch{Email: &email},
+			inDBErr:  &pq.Error{Code: "23505", Constraint: "users_email_key"},
+			outQuery: `^UPDATE users SET email = \$1 WHERE id = \$2 RETURNING`,
+			outArgs:  []driver.Value{email, mock.IDTest},
+			outErr:   service.ErrEmailTaken.Error(),
+		},
+		{
+			name:     mock.NameErrorDBClosed,
+			inPatch:  entity.UserPatch{Email: &email},
+			outQuery: `^UPDATE users SET email = \$1 WHERE id = \$2 RETURNING`,
+			outArgs:  []driver.Value{email, mock.IDTest},
+			outErr:   "sql: database is closed",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			rows := sqlmock.NewRows([]string{"id", "username", "password", "email"}).
+				AddRow(mock.IDTest, mock.UsernameTest, mock.PasswordTest, mock.EmailTest)
+
+			if tt.name == mock.NameErrorNoRows {
+				rows = sqlmock.NewRows([]string{"id", "username", "password", "email"})
+			}
+
+			query := dbMock.ExpectQuery(tt.outQuery).WithArgs(tt.outArgs...)
+			if tt.inDBErr != nil {
+				query.WillReturnError(tt.inDBErr)
+			} else {
+				query.WillReturnRows(rows)
+			}
+
+			user, err := repo.UpdateUser(context.TODO(), mock.IDTest, tt.inPatch)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.outErr == "" {
+				assert.Empty(t, resultErr)
+				assert.Equal(t, mock.IDTest, user.ID)
+				assert.Nil(t, dbMock.ExpectationsWereMet())
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestPostgresDeleteUser(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		outErr string
+		inID   int
+	}{
+		{
+			name:   mock.NameNoError,
+			inID:   mock.IDTest,
+			outErr: "",
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			inID:   mock.IDTest,
+			outErr: "sql: database is closed",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			dbMock.ExpectExec(
+				"^DELETE FROM users",
+			).WithArgs(
+				tt.inID,
+			).WillReturnResult(
+				sqlmock.NewResult(0, 1),
+			)
+
+			_, err = repo.DeleteUser(context.TODO(), tt.inID)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestPostgresCountLegacyPasswords(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name     string
+		outErr   string
+		outCount int
+	}{
+		{
+			name:     mock.NameNoError,
+			outCount: 2,
+			outErr:   "",
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			outErr: "sql: database is closed",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			dbMock.ExpectQuery(
+				`^SELECT COUNT\(\*\) FROM users WHERE LENGTH\(password\) = 64 ` +
+					`AND LTRIM\(LOWER\(password\), '0123456789abcdef'\) = ''$`,
+			).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.outCount))
+
+			count, err := repo.CountLegacyPasswords(context.TODO())
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+				assert.Equal(t, tt.outCount, count)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestPostgresUpgradePassword(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name           string
+		outErr         string
+		inRowsAffected int64
+		outUpgraded    bool
+	}{
+		{
+			name:           mock.NameNoError,
+			inRowsAffected: 1,
+			outUpgraded:    true,
+		},
+		{
+			name:           "PasswordChanged",
+			inRowsAffected: 0,
+			outUpgraded:    false,
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			outErr: "sql: database is closed",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			db, dbMock, err := sqlmock.New()
+			if err != nil {
+				assert.Error(t, err)
+			}
+			defer db.Close()
+
+			if tt.name == mock.NameErrorDBClosed {
+				db.Close()
+			}
+
+			repo := repository.NewPostgres(db)
+
+			dbMock.ExpectExec(`^UPDATE users SET password = \$1 WHERE id = \$2 AND password = \$3$`).
+				WithArgs(hashTest, mock.IDTest, mock.LegacyHashTest).
+				WillReturnResult(sqlmock.NewResult(0, tt.inRowsAffected))
+
+			upgraded, err := repo.UpgradePassword(context.TODO(), mock.IDTest, mock.LegacyHashTest, hashTest)
+			if tt.outErr == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.outUpgraded, upgraded)
+			} else {
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
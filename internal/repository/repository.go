@@ -0,0 +1,13 @@
+// Package repository holds the implementations of service.UserRepository,
+// along with those of the login attempt and API key repositories.
+package repository
+
+import (
+	"fmt"
+
+	"storage/internal/service"
+)
+
+func unknownSortFieldError(sortBy string) error {
+	return fmt.Errorf("%w: unknown sort field %q", service.ErrInvalidListOptions, sortBy)
+}
@@ -0,0 +1,476 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/service"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SQL is a service.UserRepository, service.LoginAttemptRepository and
+// auth.KeyRepository backed by a SQL database, whose differences are handled
+// by its Dialect.
+type SQL struct {
+	db      *sql.DB
+	dialect Dialect
+	tracer  trace.Tracer
+}
+
+// sortColumns holds the columns a page of users can be sorted by, so that no
+// value outside of it ever reaches the SQL text.
+var sortColumns = map[string]bool{
+	"id":       true,
+	"username": true,
+	"email":    true,
+}
+
+// legacyPasswordCondition holds the password hashes password.IsLegacy
+// reports: 64 hexadecimal digits, in either case.
+const legacyPasswordCondition = "LENGTH(password) = 64 AND LTRIM(LOWER(password), '0123456789abcdef') = ''"
+
+// NewSQL returns a SQL repository that does not trace its statements.
+func NewSQL(db *sql.DB, dialect Dialect) *SQL {
+	return &SQL{
+		db:      db,
+		dialect: dialect,
+		tracer:  trace.NewNoopTracerProvider().Tracer(instrumentationName),
+	}
+}
+
+// NewPostgres ...
+func NewPostgres(db *sql.DB) *SQL {
+	return NewSQL(db, PostgresDialect{})
+}
+
+// NewSQLite ...
+func NewSQLite(db *sql.DB) *SQL {
+	return NewSQL(db, SQLiteDialect{})
+}
+
+// WithTracerProvider returns a copy of s that traces every statement with a
+// tracer of tp.
+func (s SQL) WithTracerProvider(tp trace.TracerProvider) *SQL {
+	s.tracer = tp.Tracer(instrumentationName)
+
+	return &s
+}
+
+// ListUsers ...
+func (s SQL) ListUsers(ctx context.Context, query service.ListUsersQuery) (users []entity.User, err error) {
+	text, args, err := buildListQuery(s.dialect, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.traced(s.db).QueryContext(ctx, text, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error to get all users: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userBeta entity.User
+
+		err = rows.Scan(&userBeta.ID, &userBeta.Username, &userBeta.Email)
+		if err != nil {
+			return nil, fmt.Errorf("error to get all users: %w", err)
+		}
+
+		users = append(users, userBeta)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error to get all users: %w", err)
+	}
+
+	return users, nil
+}
+
+// GetUserByID ...
+func (s SQL) GetUserByID(ctx context.Context, id int) (user entity.User, err error) {
+	row := s.traced(s.db).QueryRowContext(
+		ctx,
+		rebind(s.dialect, "SELECT id, username, password, email FROM users WHERE id = ?"),
+		id,
+	)
+
+	err = row.Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, service.ErrUserNotFound
+		}
+
+		return entity.User{}, fmt.Errorf("error to get user by ID: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetUserByUsername ...
+func (s SQL) GetUserByUsername(ctx context.Context, username string) (user entity.User, err error) {
+	row := s.traced(s.db).QueryRowContext(
+		ctx,
+		rebind(s.dialect, "SELECT id, username, password, email FROM users WHERE username = ?"),
+		username,
+	)
+
+	err = row.Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, service.ErrUserNotFound
+		}
+
+		return entity.User{}, fmt.Errorf("error to get user by username: %w", err)
+	}
+
+	return user, nil
+}
+
+// InsertUser ...
+func (s SQL) InsertUser(ctx context.Context, user entity.User) (err error) {
+	_, err = s.traced(s.db).ExecContext(
+		ctx,
+		rebind(s.dialect, "INSERT INTO users(username, password, email) VALUES (?,?,?)"),
+		user.Username,
+		user.Password,
+		user.Email,
+	)
+	if err != nil {
+		if domainErr, ok := s.dialect.UniqueViolation(err); ok {
+			return domainErr
+		}
+
+		return fmt.Errorf("error to insert user: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUser ...
+func (s SQL) UpdateUser(ctx context.Context, id int, patch entity.UserPatch) (user entity.User, err error) {
+	var (
+		sets []string
+		args []any
+	)
+
+	set := func(column string, value *string) {
+		if value == nil {
+			return
+		}
+
+		args = append(args, *value)
+		sets = append(sets, column+" = ?")
+	}
+
+	set("username", patch.Username)
+	set("email", patch.Email)
+	set("password", patch.Password)
+
+	args = append(args, id)
+
+	// Every dialect supports RETURNING, SQLite since 3.35.
+	text := "UPDATE users SET " + strings.Join(sets, ", ") + " WHERE id = ? RETURNING id, username, password, email"
+
+	row := s.traced(s.db).QueryRowContext(ctx, rebind(s.dialect, text), args...)
+
+	err = row.Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.User{}, service.ErrUserNotFound
+		}
+
+		if domainErr, ok := s.dialect.UniqueViolation(err); ok {
+			return entity.User{}, domainErr
+		}
+
+		return entity.User{}, fmt.Errorf("error to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// DeleteUser ...
+func (s SQL) DeleteUser(ctx context.Context, id int) (rowsAffected int, err error) {
+	r, err := s.traced(s.db).ExecContext(ctx, rebind(s.dialect, "DELETE FROM users WHERE id = ?"), id)
+	if err != nil {
+		return 0, fmt.Errorf("error to delete user: %w", err)
+	}
+
+	count, _ := r.RowsAffected()
+
+	rowsAffected = int(count)
+
+	return rowsAffected, nil
+}
+
+// CountLegacyPasswords ...
+func (s SQL) CountLegacyPasswords(ctx context.Context) (count int, err error) {
+	row := s.traced(s.db).QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE "+legacyPasswordCondition)
+
+	err = row.Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error to count legacy passwords: %w", err)
+	}
+
+	return count, nil
+}
+
+// UpgradePassword ...
+func (s SQL) UpgradePassword(ctx context.Context, id int, oldHash, newHash string) (upgraded bool, err error) {
+	r, err := s.traced(s.db).ExecContext(
+		ctx,
+		rebind(s.dialect, "UPDATE users SET password = ? WHERE id = ? AND password = ?"),
+		newHash,
+		id,
+		oldHash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error to upgrade password: %w", err)
+	}
+
+	count, _ := r.RowsAffected()
+
+	return count > 0, nil
+}
+
+// GetLoginAttempts ...
+func (s SQL) GetLoginAttempts(ctx context.Context, username string) (attempts entity.LoginAttempts, err error) {
+	row := s.traced(s.db).QueryRowContext(
+		ctx,
+		rebind(s.dialect, "SELECT failures, locked_until, last_failure FROM login_attempts WHERE username = ?"),
+		username,
+	)
+
+	var lockedUntil, lastFailure sql.NullTime
+
+	err = row.Scan(&attempts.Failures, &lockedUntil, &lastFailure)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.LoginAttempts{}, nil
+		}
+
+		return entity.LoginAttempts{}, fmt.Errorf("error to get login attempts: %w", err)
+	}
+
+	attempts.LockedUntil = lockedUntil.Time
+	attempts.LastFailure = lastFailure.Time
+
+	return attempts, nil
+}
+
+// RecordLoginFailure inserts or updates the attempts with a single upsert
+// conditional on the failures, so that concurrent failures on several
+// replicas are recorded one after the other.
+func (s SQL) RecordLoginFailure(
+	ctx context.Context,
+	username string,
+	seenFailures int,
+	next entity.LoginAttempts,
+) (recorded bool, err error) {
+	lockedUntil := sql.NullTime{Time: next.LockedUntil.UTC(), Valid: !next.LockedUntil.IsZero()}
+
+	result, err := s.traced(s.db).ExecContext(
+		ctx,
+		rebind(s.dialect, "INSERT INTO login_attempts(username, failures, locked_until, last_failure) "+
+			"VALUES (?,?,?,?) ON CONFLICT (username) DO UPDATE SET failures = excluded.failures, "+
+			"locked_until = excluded.locked_until, last_failure = excluded.last_failure "+
+			"WHERE login_attempts.failures = ?"),
+		username,
+		next.Failures,
+		lockedUntil,
+		next.LastFailure.UTC(),
+		seenFailures,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error to record login failure: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error to record login failure: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// ResetLoginAttempts ...
+func (s SQL) ResetLoginAttempts(ctx context.Context, username string) (err error) {
+	_, err = s.traced(s.db).ExecContext(
+		ctx,
+		rebind(s.dialect, "DELETE FROM login_attempts WHERE username = ?"),
+		username,
+	)
+	if err != nil {
+		return fmt.Errorf("error to reset login attempts: %w", err)
+	}
+
+	return nil
+}
+
+// InsertAPIKey ...
+func (s SQL) InsertAPIKey(ctx context.Context, key entity.APIKey) (err error) {
+	_, err = s.traced(s.db).ExecContext(
+		ctx,
+		rebind(s.dialect, "INSERT INTO api_keys(name, key_hash, scopes) VALUES (?,?,?)"),
+		key.Name,
+		key.Hash,
+		strings.Join(key.Scopes, ","),
+	)
+	if err != nil {
+		if domainErr, ok := s.dialect.UniqueViolation(err); ok {
+			return domainErr
+		}
+
+		return fmt.Errorf("error to insert API key: %w", err)
+	}
+
+	return nil
+}
+
+// GetAPIKeyByHash ...
+func (s SQL) GetAPIKeyByHash(ctx context.Context, hash string) (key entity.APIKey, err error) {
+	row := s.traced(s.db).QueryRowContext(
+		ctx,
+		rebind(s.dialect, "SELECT name, key_hash, scopes, created_at FROM api_keys WHERE key_hash = ?"),
+		hash,
+	)
+
+	key, err = scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.APIKey{}, auth.ErrKeyNotFound
+		}
+
+		return entity.APIKey{}, fmt.Errorf("error to get API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns every API key by name.
+func (s SQL) ListAPIKeys(ctx context.Context) (keys []entity.APIKey, err error) {
+	rows, err := s.traced(s.db).QueryContext(
+		ctx,
+		"SELECT name, key_hash, scopes, created_at FROM api_keys ORDER BY name",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key entity.APIKey
+
+		key, err = scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error to list API keys: %w", err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey ...
+func (s SQL) DeleteAPIKey(ctx context.Context, name string) (rowsAffected int, err error) {
+	r, err := s.traced(s.db).ExecContext(ctx, rebind(s.dialect, "DELETE FROM api_keys WHERE name = ?"), name)
+	if err != nil {
+		return 0, fmt.Errorf("error to delete API key: %w", err)
+	}
+
+	count, _ := r.RowsAffected()
+
+	rowsAffected = int(count)
+
+	return rowsAffected, nil
+}
+
+// scanAPIKey reads an API key from a row of name, key_hash, scopes and
+// created_at, the scopes being comma separated.
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (key entity.APIKey, err error) {
+	var scopes string
+
+	err = row.Scan(&key.Name, &key.Hash, &scopes, &key.CreatedAt)
+	if err != nil {
+		return entity.APIKey{}, err
+	}
+
+	key.Scopes = strings.Split(scopes, ",")
+
+	return key, nil
+}
+
+// escapeLike escapes the LIKE wildcards of s, using \ as escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// buildListQuery returns the keyset pagination query for query. The sort
+// column comes from a closed set, every other value is a bound argument.
+func buildListQuery(dialect Dialect, query service.ListUsersQuery) (text string, args []any, err error) {
+	if !sortColumns[query.SortBy] {
+		return "", nil, unknownSortFieldError(query.SortBy)
+	}
+
+	var where []string
+
+	arg := func(value any) string {
+		args = append(args, value)
+
+		return dialect.Placeholder(len(args))
+	}
+
+	if query.UsernamePrefix != "" {
+		where = append(where, fmt.Sprintf(`username LIKE %s ESCAPE '\'`, arg(escapeLike(query.UsernamePrefix)+"%")))
+	}
+
+	if query.EmailDomain != "" {
+		where = append(where, fmt.Sprintf(`LOWER(email) LIKE %s ESCAPE '\'`, arg("%@"+escapeLike(query.EmailDomain))))
+	}
+
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if query.HasCursor {
+		if query.SortBy == "id" {
+			where = append(where, fmt.Sprintf("id %s %s", comparison, arg(query.AfterID)))
+		} else {
+			where = append(where, fmt.Sprintf(
+				"(%s, id) %s (%s, %s)",
+				query.SortBy,
+				comparison,
+				arg(query.AfterValue),
+				arg(query.AfterID),
+			))
+		}
+	}
+
+	text = "SELECT id, username, email FROM users"
+	if len(where) > 0 {
+		text += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	if query.SortBy == "id" {
+		text += fmt.Sprintf(" ORDER BY id %s", direction)
+	} else {
+		text += fmt.Sprintf(" ORDER BY %s %s, id %s", query.SortBy, direction, direction)
+	}
+
+	text += " LIMIT " + arg(query.Limit)
+
+	return text, args, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryer runs statements, either on a *sql.DB or in a *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// tracedQueryer runs every statement of q in a span of its own. Only the
+// statement text is recorded, never its bound values.
+type tracedQueryer struct {
+	q       queryer
+	tracer  trace.Tracer
+	dialect Dialect
+}
+
+// instrumentationName names the tracer of the repository.
+const instrumentationName = "storage/internal/repository"
+
+// traced returns q tracing its statements with the tracer of s.
+func (s SQL) traced(q queryer) tracedQueryer {
+	return tracedQueryer{q: q, tracer: s.tracer, dialect: s.dialect}
+}
+
+// ExecContext ...
+func (t tracedQueryer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+
+	result, err := t.q.ExecContext(ctx, query, args...)
+	end(span, err)
+
+	return result, err
+}
+
+// QueryContext traces running the query, not reading its rows.
+func (t tracedQueryer) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+
+	rows, err := t.q.QueryContext(ctx, query, args...)
+	end(span, err)
+
+	return rows, err
+}
+
+// QueryRowContext ...
+func (t tracedQueryer) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := t.start(ctx, query)
+
+	row := t.q.QueryRowContext(ctx, query, args...)
+	end(span, row.Err())
+
+	return row
+}
+
+// start starts the span of query, named after its operation, e.g. SELECT.
+func (t tracedQueryer) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+
+	return t.tracer.Start(
+		ctx,
+		operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(t.dialect.System()),
+			semconv.DBOperationKey.String(operation),
+			semconv.DBStatementKey.String(query),
+		),
+	)
+}
+
+// end ends span, marking it as failed when err is not nil.
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
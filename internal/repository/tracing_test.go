@@ -0,0 +1,66 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSQLTracing(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name         string
+		inUsername   string
+		outStatement string
+		outStatus    codes.Code
+	}{
+		{
+			name:         mock.NameNoError,
+			inUsername:   "dave",
+			outStatement: "INSERT INTO users(username, password, email) VALUES (?,?,?)",
+			outStatus:    codes.Unset,
+		},
+		{
+			name:         "ErrorUsernameTaken",
+			inUsername:   "carol",
+			outStatement: "INSERT INTO users(username, password, email) VALUES (?,?,?)",
+			outStatus:    codes.Error,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := tracetest.NewSpanRecorder()
+			repo := newSQLite(t).WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+			_ = repo.InsertUser(context.TODO(), entity.User{
+				Username: tt.inUsername,
+				Password: hashTest,
+				Email:    tt.inUsername + "@example.org",
+			})
+
+			spans := recorder.Ended()
+			if assert.Len(t, spans, 1) {
+				assert.Equal(t, "INSERT", spans[0].Name())
+				assert.Equal(t, tt.outStatus, spans[0].Status().Code)
+				assert.Contains(t, spans[0].Attributes(), attribute.String("db.system", "sqlite"))
+				assert.Contains(t, spans[0].Attributes(), attribute.String("db.statement", tt.outStatement))
+
+				for _, kv := range spans[0].Attributes() {
+					assert.NotContains(t, kv.Value.Emit(), tt.inUsername)
+					assert.NotContains(t, kv.Value.Emit(), hashTest)
+				}
+			}
+		})
+	}
+}
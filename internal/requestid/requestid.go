@@ -0,0 +1,53 @@
+// Package requestid carries the ID of the HTTP request being served, taken
+// from the X-Request-ID header or generated, through the context.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+const (
+	// Header is the HTTP header holding the request ID.
+	Header = "X-Request-ID"
+
+	// maxLength bounds the length of the request IDs taken from callers.
+	maxLength = 128
+)
+
+// New returns a random request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether id, taken from a caller, may be used as a request ID,
+// i.e. whether it is made of 1 to 128 printable ASCII characters, so that it
+// is safe to log and to echo in a header.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or "" if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+
+	return id
+}
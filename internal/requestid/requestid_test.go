@@ -0,0 +1,45 @@
+package requestid_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"storage/internal/requestid"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		in   string
+		out  bool
+	}{
+		{name: "UUID", in: "6ba7b810-9dad-11d1-80b4-00c04fd430c8", out: true},
+		{name: "Printable", in: "caller/42 #1", out: true},
+		{name: "Empty", in: "", out: false},
+		{name: "TooLong", in: strings.Repeat("a", 129), out: false},
+		{name: "ControlCharacter", in: "abc\x1b[31m", out: false},
+		{name: "NonASCII", in: "ñandú", out: false},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.out, requestid.Valid(tt.in))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	id := requestid.New()
+
+	assert.True(t, requestid.Valid(id))
+	assert.NotEqual(t, id, requestid.New())
+	assert.Equal(t, id, requestid.FromContext(requestid.NewContext(context.TODO(), id)))
+}
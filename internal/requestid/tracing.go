@@ -0,0 +1,36 @@
+package requestid
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// SpanProcessor records the request ID of the context every span starts in
+// as its request.id attribute, so that the spans of a request can be found
+// from its ID.
+type SpanProcessor struct{}
+
+// AttributeKey is the span attribute holding the request ID.
+const AttributeKey = attribute.Key("request.id")
+
+// OnStart ...
+func (SpanProcessor) OnStart(ctx context.Context, span sdktrace.ReadWriteSpan) {
+	if id := FromContext(ctx); id != "" {
+		span.SetAttributes(AttributeKey.String(id))
+	}
+}
+
+// OnEnd ...
+func (SpanProcessor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+// Shutdown ...
+func (SpanProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+// ForceFlush ...
+func (SpanProcessor) ForceFlush(context.Context) error {
+	return nil
+}
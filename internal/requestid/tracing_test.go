@@ -0,0 +1,38 @@
+package requestid_test
+
+import (
+	"context"
+	"testing"
+
+	"storage/internal/requestid"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpanProcessor(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(requestid.SpanProcessor{}),
+		sdktrace.WithSpanProcessor(recorder),
+	).Tracer("test")
+
+	ctx, parent := tracer.Start(requestid.NewContext(context.TODO(), "abc-123"), "parent")
+	_, child := tracer.Start(ctx, "child")
+
+	child.End()
+	parent.End()
+
+	_, other := tracer.Start(context.TODO(), "other")
+	other.End()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 3) {
+		assert.Contains(t, spans[0].Attributes(), requestid.AttributeKey.String("abc-123"))
+		assert.Contains(t, spans[1].Attributes(), requestid.AttributeKey.String("abc-123"))
+		assert.Empty(t, spans[2].Attributes())
+	}
+}
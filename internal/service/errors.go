@@ -0,0 +1,12 @@
+package service
+
+import "errors"
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUsernameTaken      = errors.New("username already taken")
+	ErrEmailTaken         = errors.New("email already taken")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidListOptions = errors.New("invalid list options")
+	ErrUserLocked         = errors.New("user locked")
+)
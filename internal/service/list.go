@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"storage/internal/entity"
+)
+
+// listCursor is the position after which the next page starts: the sort
+// value and ID of the last user of the previous page.
+type listCursor struct {
+	Value string `json:"v,omitempty"`
+	ID    int    `json:"id"`
+}
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 500
+)
+
+// sortFields holds the accepted sort fields, the empty one meaning "id".
+var sortFields = map[string]string{
+	"":         "id",
+	"id":       "id",
+	"username": "username",
+	"email":    "email",
+}
+
+func encodeCursor(sortBy string, user entity.User) string {
+	cursor := listCursor{ID: user.ID}
+
+	switch sortBy {
+	case "username":
+		cursor.Value = user.Username
+	case "email":
+		cursor.Value = user.Email
+	}
+
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string) (cursor listCursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return listCursor{}, fmt.Errorf("%w: invalid cursor", ErrInvalidListOptions)
+	}
+
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return listCursor{}, fmt.Errorf("%w: invalid cursor", ErrInvalidListOptions)
+	}
+
+	return cursor, nil
+}
+
+// buildListQuery validates opts and turns them into the query handed to the
+// repository, which asks for one user more than the page size to know
+// whether there is a next page.
+func buildListQuery(opts entity.ListUsersRequest) (query ListUsersQuery, pageSize int, err error) {
+	sortBy, ok := sortFields[opts.SortBy]
+	if !ok {
+		return ListUsersQuery{}, 0, fmt.Errorf("%w: unknown sort field %q", ErrInvalidListOptions, opts.SortBy)
+	}
+
+	pageSize = opts.Limit
+
+	switch {
+	case pageSize <= 0:
+		pageSize = DefaultListLimit
+	case pageSize > MaxListLimit:
+		pageSize = MaxListLimit
+	}
+
+	query = ListUsersQuery{
+		SortBy:         sortBy,
+		Descending:     opts.Descending,
+		UsernamePrefix: opts.UsernamePrefix,
+		EmailDomain:    strings.ToLower(strings.TrimPrefix(opts.EmailDomain, "@")),
+		Limit:          pageSize + 1,
+	}
+
+	if opts.Cursor != "" {
+		var cursor listCursor
+
+		cursor, err = decodeCursor(opts.Cursor)
+		if err != nil {
+			return ListUsersQuery{}, 0, err
+		}
+
+		query.HasCursor = true
+		query.AfterValue = cursor.Value
+		query.AfterID = cursor.ID
+	}
+
+	return query, pageSize, nil
+}
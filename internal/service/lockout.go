@@ -0,0 +1,89 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"storage/internal/entity"
+)
+
+// LockoutPolicy locks a username out of GetUserByUsernameAndPassword after
+// Threshold consecutive failed credential checks. The first lockout lasts
+// Duration and every further failure, once it expires, doubles it up to
+// MaxDuration. A successful check, UnlockUser or FailureWindow without
+// failures starts over.
+type LockoutPolicy struct {
+	// Threshold is the number of failures that locks a username out, 0 to
+	// never lock it.
+	Threshold int
+	// Duration is how long the first lockout lasts.
+	Duration time.Duration
+	// MaxDuration caps every lockout, 0 for no cap.
+	MaxDuration time.Duration
+	// FailureWindow is how long the failures are kept after the last one,
+	// or after the lockout they caused, 0 to keep them until a success.
+	FailureWindow time.Duration
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+// Enabled reports whether p ever locks a username out.
+func (p LockoutPolicy) Enabled() bool {
+	return p.Threshold > 0
+}
+
+// now returns the current time of p.
+func (p LockoutPolicy) now() time.Time {
+	if p.Now == nil {
+		return time.Now()
+	}
+
+	return p.Now()
+}
+
+// LockDuration returns how long failures consecutive failures lock a
+// username out, which is 0 below the threshold.
+func (p LockoutPolicy) LockDuration(failures int) time.Duration {
+	if !p.Enabled() || failures < p.Threshold {
+		return 0
+	}
+
+	duration := p.Duration
+
+	// Doubling stops at the cap, or before it overflows.
+	for i := p.Threshold; i < failures && duration <= math.MaxInt64/2; i++ {
+		if p.MaxDuration > 0 && duration >= p.MaxDuration {
+			break
+		}
+
+		duration *= 2
+	}
+
+	if p.MaxDuration > 0 && duration > p.MaxDuration {
+		return p.MaxDuration
+	}
+
+	return duration
+}
+
+// nextAttempts returns attempts after one more failure at now, locked out if
+// it reaches the threshold.
+func (p LockoutPolicy) nextAttempts(attempts entity.LoginAttempts, now time.Time) entity.LoginAttempts {
+	last := attempts.LastFailure
+	if attempts.LockedUntil.After(last) {
+		last = attempts.LockedUntil
+	}
+
+	failures := attempts.Failures
+	if p.FailureWindow > 0 && now.Sub(last) >= p.FailureWindow {
+		failures = 0
+	}
+
+	next := entity.LoginAttempts{LastFailure: now, Failures: failures + 1}
+
+	if duration := p.LockDuration(next.Failures); duration > 0 {
+		next.LockedUntil = now.Add(duration)
+	}
+
+	return next
+}
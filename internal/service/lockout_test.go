@@ -0,0 +1,290 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/password"
+	"storage/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLockoutPolicyLockDuration(t *testing.T) {
+	t.Parallel()
+
+	policy := service.LockoutPolicy{Threshold: 3, Duration: time.Minute, MaxDuration: 10 * time.Minute}
+
+	for _, tt := range []struct {
+		name        string
+		inPolicy    service.LockoutPolicy
+		inFailures  int
+		outDuration time.Duration
+	}{
+		{name: "BelowThreshold", inPolicy: policy, inFailures: 2, outDuration: 0},
+		{name: "Threshold", inPolicy: policy, inFailures: 3, outDuration: time.Minute},
+		{name: "Doubled", inPolicy: policy, inFailures: 5, outDuration: 4 * time.Minute},
+		{name: "Capped", inPolicy: policy, inFailures: 7, outDuration: 10 * time.Minute},
+		{
+			name:        "Overflow",
+			inPolicy:    service.LockoutPolicy{Threshold: 1, Duration: time.Minute},
+			inFailures:  200,
+			outDuration: time.Minute << 27,
+		},
+		{name: "Disabled", inPolicy: service.LockoutPolicy{Duration: time.Minute}, inFailures: 9, outDuration: 0},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.outDuration, tt.inPolicy.LockDuration(tt.inFailures))
+		})
+	}
+}
+
+func TestGetUserByUsernameAndPasswordLockout(t *testing.T) {
+	t.Parallel()
+
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+	policy := service.LockoutPolicy{Threshold: 2, Duration: time.Hour}
+
+	for _, tt := range []struct {
+		name       string
+		inUsername string
+		// inPasswords are checked in order before mock.PasswordTest.
+		inPasswords []string
+		outErr      error
+	}{
+		{
+			name:        mock.NameNoError,
+			inUsername:  mock.UsernameTest,
+			inPasswords: []string{"wrong"},
+		},
+		{
+			name:        "Locked",
+			inUsername:  mock.UsernameTest,
+			inPasswords: []string{"wrong", "wrong"},
+			outErr:      service.ErrUserLocked,
+		},
+		{
+			name:        "LockedWithoutUser",
+			inUsername:  otherUsernameTest,
+			inPasswords: []string{"wrong", "wrong"},
+			outErr:      service.ErrUserLocked,
+		},
+		{
+			name:        "ResetOnSuccess",
+			inUsername:  mock.UsernameTest,
+			inPasswords: []string{"wrong", mock.PasswordTest, "wrong"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newMemoryRepository(t, hasher)
+			svc := service.GetService(repo, hasher).WithLockout(repo, policy)
+
+			for _, plainPassword := range tt.inPasswords {
+				_, _ = svc.GetUserByUsernameAndPassword(context.TODO(), tt.inUsername, plainPassword)
+			}
+
+			_, err := svc.GetUserByUsernameAndPassword(context.TODO(), tt.inUsername, mock.PasswordTest)
+
+			if tt.outErr != nil {
+				assert.ErrorIs(t, err, tt.outErr)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestGetUserByUsernameAndPasswordLockoutBackoff(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+	repo := newMemoryRepository(t, hasher)
+	svc := service.GetService(repo, hasher).WithLockout(repo, service.LockoutPolicy{
+		Threshold: 1,
+		Duration:  time.Minute,
+		Now:       func() time.Time { return now },
+	})
+
+	_, err := svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	_, err = svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+	assert.ErrorIs(t, err, service.ErrUserLocked)
+
+	// Once the first lockout expires, the next failure locks twice as long.
+	now = now.Add(time.Minute)
+
+	_, err = svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	attempts, err := repo.GetLoginAttempts(context.TODO(), mock.UsernameTest)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempts.Failures)
+	assert.Equal(t, now.Add(2*time.Minute), attempts.LockedUntil)
+}
+
+// TestGetUserByUsernameAndPasswordLockoutConcurrent checks that concurrent
+// checks cannot get more passwords checked than the threshold allows.
+func TestGetUserByUsernameAndPasswordLockoutConcurrent(t *testing.T) {
+	t.Parallel()
+
+	const checks = 20
+
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+	repo := newMemoryRepository(t, hasher)
+	svc := service.GetService(repo, hasher).
+		WithLockout(repo, service.LockoutPolicy{Threshold: 3, Duration: time.Hour})
+
+	var (
+		wg      sync.WaitGroup
+		checked atomic.Int32
+	)
+
+	for i := 0; i < checks; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+			if errors.Is(err, service.ErrInvalidCredentials) {
+				checked.Add(1)
+			} else {
+				assert.ErrorIs(t, err, service.ErrUserLocked)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, int32(3), checked.Load())
+}
+
+func TestGetUserByUsernameAndPasswordLockoutFailureWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+	repo := newMemoryRepository(t, hasher)
+	svc := service.GetService(repo, hasher).WithLockout(repo, service.LockoutPolicy{
+		Threshold:     2,
+		Duration:      time.Minute,
+		FailureWindow: time.Hour,
+		Now:           func() time.Time { return now },
+	})
+
+	_, err := svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	// A failure an hour later starts over instead of locking out.
+	now = now.Add(time.Hour)
+
+	_, err = svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	attempts, err := repo.GetLoginAttempts(context.TODO(), mock.UsernameTest)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.LoginAttempts{LastFailure: now, Failures: 1}, attempts)
+
+	// The window of a lockout starts when it ends.
+	_, err = svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	now = now.Add(time.Minute + 30*time.Minute)
+
+	_, err = svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+	attempts, err = repo.GetLoginAttempts(context.TODO(), mock.UsernameTest)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts.Failures)
+	assert.Equal(t, now.Add(2*time.Minute), attempts.LockedUntil)
+}
+
+func TestGetUserByUsernameAndPasswordLockoutDBClosed(t *testing.T) {
+	t.Parallel()
+
+	hasher := password.NewBcrypt(bcrypt.MinCost)
+	svc := service.GetService(newMemoryRepository(t, hasher), hasher).
+		WithLockout(mock.FailingRepository{}, service.LockoutPolicy{Threshold: 1, Duration: time.Hour})
+
+	_, err := svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, mock.PasswordTest)
+	assert.ErrorContains(t, err, mock.ErrDatabaseClosed)
+}
+
+func TestUnlockUser(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		outErr string
+		inID   int
+	}{
+		{
+			name:   mock.NameNoError,
+			inID:   mock.IDTest,
+			outErr: "",
+		},
+		{
+			name:   mock.NameErrorNoRows,
+			inID:   mock.IDTest + 9,
+			outErr: service.ErrUserNotFound.Error(),
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			inID:   mock.IDTest,
+			outErr: mock.ErrDatabaseClosed,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+			policy := service.LockoutPolicy{Threshold: 1, Duration: time.Hour}
+
+			repo := newMemoryRepository(t, hasher)
+			svc := service.GetService(repo, hasher).WithLockout(repo, policy)
+
+			if tt.name == mock.NameErrorDBClosed {
+				svc = service.GetService(repo, hasher).WithLockout(mock.FailingRepository{}, policy)
+			}
+
+			_, _ = svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+
+			err := svc.UnlockUser(context.TODO(), tt.inID)
+			if err != nil {
+				resultErr = err.Error()
+			}
+
+			if tt.name != mock.NameNoError {
+				assert.Contains(t, resultErr, tt.outErr)
+
+				return
+			}
+
+			assert.Empty(t, resultErr)
+
+			_, err = svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, mock.PasswordTest)
+			assert.Nil(t, err)
+		})
+	}
+}
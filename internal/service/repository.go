@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"storage/internal/entity"
+)
+
+// UserRepository persists users beneath the service. Implementations report
+// a missing user with ErrUserNotFound and a duplicate username or email with
+// ErrUsernameTaken or ErrEmailTaken. Passwords reach the repository already
+// hashed.
+type UserRepository interface {
+	ListUsers(ctx context.Context, query ListUsersQuery) ([]entity.User, error)
+	GetUserByID(ctx context.Context, id int) (entity.User, error)
+	GetUserByUsername(ctx context.Context, username string) (entity.User, error)
+	InsertUser(ctx context.Context, user entity.User) error
+	UpdateUser(ctx context.Context, id int, patch entity.UserPatch) (entity.User, error)
+	DeleteUser(ctx context.Context, id int) (rowsAffected int, err error)
+	// CountLegacyPasswords counts the password hashes password.IsLegacy
+	// reports.
+	CountLegacyPasswords(ctx context.Context) (int, error)
+	// UpgradePassword replaces the password hash of the user with the given
+	// ID by newHash only if it still is oldHash, reporting whether it did.
+	UpgradePassword(ctx context.Context, id int, oldHash, newHash string) (upgraded bool, err error)
+}
+
+// LoginAttemptRepository persists the failed credential checks of every
+// username, whether or not a user has it, so that lockouts survive restarts
+// and are shared by every replica.
+type LoginAttemptRepository interface {
+	// GetLoginAttempts returns the zero LoginAttempts for a username without
+	// failed checks.
+	GetLoginAttempts(ctx context.Context, username string) (entity.LoginAttempts, error)
+	// RecordLoginFailure replaces the attempts of username by next only if
+	// it still has seenFailures, as no concurrent check recorded its own
+	// failure in the meantime, reporting whether it did.
+	RecordLoginFailure(
+		ctx context.Context,
+		username string,
+		seenFailures int,
+		next entity.LoginAttempts,
+	) (recorded bool, err error)
+	// ResetLoginAttempts forgets the failures and the lockout of username.
+	ResetLoginAttempts(ctx context.Context, username string) error
+}
+
+// ListUsersQuery is a validated page request over users. Users are ordered by
+// SortBy and then by ID, both in the same direction, and only those strictly
+// after the (AfterValue, AfterID) position are returned when HasCursor is set.
+// Only id, username and email are returned for each user.
+type ListUsersQuery struct {
+	// SortBy is one of "id", "username" or "email".
+	SortBy string
+	// UsernamePrefix, if not empty, keeps users whose username starts with it.
+	UsernamePrefix string
+	// EmailDomain, if not empty, keeps users whose email, compared in lower
+	// case, ends in "@" + EmailDomain. It is already lower case.
+	EmailDomain string
+	AfterValue  string
+	AfterID     int
+	Limit       int
+	HasCursor   bool
+	Descending  bool
+}
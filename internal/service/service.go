@@ -1,135 +1,306 @@
 package service
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"storage/internal/entity"
+	"storage/internal/password"
 )
 
 type Service interface {
-	GetAllUsers() ([]entity.User, error)
-	GetUserByID(int) (entity.User, error)
-	GetUserByUsernameAndPassword(string, string) (entity.User, error)
-	GetIDByUsername(string) (int, error)
-	InsertUser(string, string, string) error
-	DeleteUser(int) (int, error)
+	GetAllUsers(context.Context, entity.ListUsersRequest) ([]entity.User, string, error)
+	GetUserByID(context.Context, int) (entity.User, error)
+	GetUserByUsernameAndPassword(context.Context, string, string) (entity.User, error)
+	GetIDByUsername(context.Context, string) (int, error)
+	InsertUser(context.Context, string, string, string) error
+	UpdateUser(context.Context, int, entity.UserPatch) (entity.User, error)
+	DeleteUser(context.Context, int) (int, error)
+	CountLegacyPasswords(context.Context) (int, error)
+	UnlockUser(context.Context, int) error
+}
+
+// PasswordHasher hashes passwords into self-describing encoded strings and
+// verifies plain passwords against them. NeedsRehash reports whether a hash
+// is not of the algorithm Hash uses, and is replaced on the next successful
+// login.
+type PasswordHasher interface {
+	Hash(password string) (encodedHash string, err error)
+	Verify(password, encodedHash string) (ok bool, err error)
+	NeedsRehash(encodedHash string) bool
 }
 
 // service ...
 type service struct {
-	db *sql.DB
+	repo     UserRepository
+	hasher   PasswordHasher
+	attempts LoginAttemptRepository
+	dummy    *dummyHash
+	lockout  LockoutPolicy
 }
 
-// GetService ...
-func GetService(db *sql.DB) *service {
-	return &service{db: db}
+// dummyHash is the hash a password is verified against when its username has
+// no user, so that the time it takes does not tell whether the username
+// exists. It is hashed on first use.
+type dummyHash struct {
+	once sync.Once
+	hash string
 }
 
-// GetAllUsers ...
-func (s service) GetAllUsers() (users []entity.User, err error) {
-	rows, err := s.db.Query("SELECT id, username, email FROM users")
-	if err != nil {
-		return nil, fmt.Errorf("error to get all users: %w", err)
-	}
-	defer rows.Close()
+// dummyPassword is the password hashed into the dummyHash.
+const dummyPassword = "dummy password"
+
+// GetService returns a service that never locks a username out.
+func GetService(repo UserRepository, hasher PasswordHasher) *service {
+	return &service{repo: repo, hasher: hasher, dummy: &dummyHash{}}
+}
 
-	for rows.Next() {
-		var userBeta entity.User
+// WithLockout returns a copy of s that records the failed credential checks
+// in attempts and locks usernames out as policy says.
+func (s service) WithLockout(attempts LoginAttemptRepository, policy LockoutPolicy) *service {
+	s.attempts = attempts
+	s.lockout = policy
 
-		err = rows.Scan(&userBeta.ID, &userBeta.Username, &userBeta.Email)
-		if err != nil {
-			return nil, fmt.Errorf("error to get all users: %w", err)
-		}
+	return &s
+}
+
+// lockoutEnabled reports whether s tracks failed credential checks.
+func (s service) lockoutEnabled() bool {
+	return s.attempts != nil && s.lockout.Enabled()
+}
 
-		users = append(users, userBeta)
+// GetAllUsers returns a page of users filtered and sorted by opts, together
+// with the cursor of the next page, which is empty on the last one.
+func (s service) GetAllUsers(
+	ctx context.Context,
+	opts entity.ListUsersRequest,
+) (users []entity.User, nextCursor string, err error) {
+	query, pageSize, err := buildListQuery(opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	users, err = s.repo.ListUsers(ctx, query)
+	if err != nil {
+		return nil, "", err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error to get all users: %w", err)
+	if len(users) > pageSize {
+		users = users[:pageSize]
+		nextCursor = encodeCursor(query.SortBy, users[pageSize-1])
 	}
 
-	return users, nil
+	return users, nextCursor, nil
 }
 
 // GetUserByID ...
-func (s service) GetUserByID(id int) (user entity.User, err error) {
-	row := s.db.QueryRow("SELECT id, username, password, email FROM users WHERE id = $1", id)
+func (s service) GetUserByID(ctx context.Context, id int) (user entity.User, err error) {
+	return s.repo.GetUserByID(ctx, id)
+}
+
+// GetUserByUsernameAndPassword returns the user with the given credentials.
+// With a lockout policy, a locked out username fails with ErrUserLocked
+// before its password is checked, whether or not a user has it. Every check
+// is recorded as a failure, and locks the username out at the threshold,
+// before the password is checked, so that concurrent checks cannot get past
+// the threshold. A successful check forgets the failures.
+func (s service) GetUserByUsernameAndPassword(
+	ctx context.Context,
+	username, plainPassword string,
+) (user entity.User, err error) {
+	if !s.lockoutEnabled() {
+		return s.checkCredentials(ctx, username, plainPassword)
+	}
+
+	if err = s.recordLoginFailure(ctx, username); err != nil {
+		return entity.User{}, err
+	}
 
-	err = row.Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+	user, err = s.checkCredentials(ctx, username, plainPassword)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return entity.User{}, nil
-		}
+		return entity.User{}, err
+	}
 
-		return entity.User{}, fmt.Errorf("error to get user by ID: %w", err)
+	if err = s.attempts.ResetLoginAttempts(ctx, username); err != nil {
+		return entity.User{}, fmt.Errorf("error to reset login attempts: %w", err)
 	}
 
 	return user, nil
 }
 
-// GetUserByUsernameAndPassword ...
-func (s service) GetUserByUsernameAndPassword(username, password string) (user entity.User, err error) {
-	row := s.db.QueryRow(
-		"SELECT id, username, password, email FROM users WHERE username = $1 AND password = $2",
-		username,
-		password,
-	)
+// recordLoginFailure counts a credential check of username as failed, locking
+// it out as the lockout policy says, or fails with ErrUserLocked while it is
+// locked out. It retries when a concurrent check recorded its failure first.
+func (s service) recordLoginFailure(ctx context.Context, username string) error {
+	for {
+		attempts, err := s.attempts.GetLoginAttempts(ctx, username)
+		if err != nil {
+			return fmt.Errorf("error to get login attempts: %w", err)
+		}
+
+		now := s.lockout.now()
+		if now.Before(attempts.LockedUntil) {
+			return ErrUserLocked
+		}
 
-	err = row.Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+		recorded, err := s.attempts.RecordLoginFailure(
+			ctx,
+			username,
+			attempts.Failures,
+			s.lockout.nextAttempts(attempts, now),
+		)
+		if err != nil {
+			return fmt.Errorf("error to record login failure: %w", err)
+		}
+
+		if recorded {
+			return nil
+		}
+	}
+}
+
+// checkCredentials returns the user with the given credentials, rehashing its
+// password with the current hasher when its hash is legacy or of another
+// algorithm.
+func (s service) checkCredentials(ctx context.Context, username, plainPassword string) (entity.User, error) {
+	user, err := s.repo.GetUserByUsername(ctx, username)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return entity.User{}, nil
+		if errors.Is(err, ErrUserNotFound) {
+			s.verifyDummy(plainPassword)
+
+			return entity.User{}, ErrInvalidCredentials
 		}
 
 		return entity.User{}, fmt.Errorf("error to get user by username and password: %w", err)
 	}
 
-	return user, nil
+	var ok bool
+
+	if password.IsLegacy(user.Password) {
+		ok = password.VerifyLegacy(plainPassword, user.Password)
+	} else {
+		ok, err = s.hasher.Verify(plainPassword, user.Password)
+		if err != nil {
+			return entity.User{}, fmt.Errorf("error to get user by username and password: %w", err)
+		}
+	}
+
+	if !ok {
+		return entity.User{}, ErrInvalidCredentials
+	}
+
+	if !s.hasher.NeedsRehash(user.Password) {
+		return user, nil
+	}
+
+	return s.rehashPassword(ctx, user, plainPassword)
 }
 
-// GetIDByUsername ...
-func (s service) GetIDByUsername(username string) (id int, err error) {
-	row := s.db.QueryRow("SELECT id FROM users WHERE username = $1", username)
+// verifyDummy verifies plainPassword against the dummyHash, taking as long as
+// verifying it against the hash of a user would.
+func (s service) verifyDummy(plainPassword string) {
+	s.dummy.once.Do(func() {
+		// Without a hash, Verify fails right away, which only makes the
+		// unknown usernames faster to check.
+		s.dummy.hash, _ = s.hasher.Hash(dummyPassword)
+	})
+
+	_, _ = s.hasher.Verify(plainPassword, s.dummy.hash)
+}
 
-	err = row.Scan(&id)
+// rehashPassword replaces the verified password hash of user with a hash from
+// the current hasher, unless the password changed in the meantime.
+func (s service) rehashPassword(ctx context.Context, user entity.User, plainPassword string) (entity.User, error) {
+	passwordHashed, err := s.hasher.Hash(plainPassword)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return 0, nil
-		}
+		return entity.User{}, fmt.Errorf("error to rehash password: %w", err)
+	}
 
-		return 0, fmt.Errorf("error to get ID by username: %w", err)
+	upgraded, err := s.repo.UpgradePassword(ctx, user.ID, user.Password, passwordHashed)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("error to rehash password: %w", err)
+	}
+
+	if upgraded {
+		user.Password = passwordHashed
+	}
+
+	return user, nil
+}
+
+// GetIDByUsername ...
+func (s service) GetIDByUsername(ctx context.Context, username string) (id int, err error) {
+	user, err := s.repo.GetUserByUsername(ctx, username)
+	if err != nil {
+		return 0, err
 	}
 
-	return id, nil
+	return user.ID, nil
 }
 
 // InsertUser ...
-func (s *service) InsertUser(username, password, email string) (err error) {
-	_, err = s.db.Exec(
-		"INSERT INTO users(username, password, email) VALUES ($1,$2,$3)",
-		username,
-		password,
-		email,
-	)
+func (s *service) InsertUser(ctx context.Context, username, plainPassword, email string) (err error) {
+	passwordHashed, err := s.hasher.Hash(plainPassword)
 	if err != nil {
 		return fmt.Errorf("error to insert user: %w", err)
 	}
 
-	return nil
+	return s.repo.InsertUser(ctx, entity.User{
+		Username: username,
+		Password: passwordHashed,
+		Email:    email,
+	})
+}
+
+// UpdateUser applies the non-nil fields of patch to the user with the given
+// ID, hashing the new password if any, and returns the updated user.
+func (s *service) UpdateUser(ctx context.Context, id int, patch entity.UserPatch) (user entity.User, err error) {
+	if patch.Username == nil && patch.Email == nil && patch.Password == nil {
+		return s.repo.GetUserByID(ctx, id)
+	}
+
+	if patch.Password != nil {
+		var passwordHashed string
+
+		passwordHashed, err = s.hasher.Hash(*patch.Password)
+		if err != nil {
+			return entity.User{}, fmt.Errorf("error to update user: %w", err)
+		}
+
+		patch.Password = &passwordHashed
+	}
+
+	return s.repo.UpdateUser(ctx, id, patch)
 }
 
 // DeleteUser ...
-func (s *service) DeleteUser(id int) (rowsAffected int, err error) {
-	r, err := s.db.Exec("DELETE FROM users WHERE id = $1", id)
+func (s *service) DeleteUser(ctx context.Context, id int) (rowsAffected int, err error) {
+	return s.repo.DeleteUser(ctx, id)
+}
+
+// CountLegacyPasswords returns how many users still have a legacy SHA-256
+// password hash, which is upgraded on their next successful login.
+func (s service) CountLegacyPasswords(ctx context.Context) (count int, err error) {
+	return s.repo.CountLegacyPasswords(ctx)
+}
+
+// UnlockUser forgets the failed credential checks of the user with the given
+// ID and lifts its lockout, if any.
+func (s service) UnlockUser(ctx context.Context, id int) (err error) {
+	user, err := s.repo.GetUserByID(ctx, id)
 	if err != nil {
-		return 0, fmt.Errorf("error to delete user: %w", err)
+		return err
 	}
 
-	count, _ := r.RowsAffected()
+	if s.attempts == nil {
+		return nil
+	}
 
-	rowsAffected = int(count)
+	if err = s.attempts.ResetLoginAttempts(ctx, user.Username); err != nil {
+		return fmt.Errorf("error to unlock user: %w", err)
+	}
 
-	return rowsAffected, nil
+	return nil
 }
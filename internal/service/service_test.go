@@ -1,51 +1,70 @@
 package service_test
 
 import (
+	"context"
+	"sync/atomic"
 	"testing"
 
+	"storage/internal/entity"
 	"storage/internal/entity/mock"
+	"storage/internal/password"
+	"storage/internal/repository"
 	"storage/internal/service"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	otherUsernameTest = "other"
+	otherEmailTest    = "other@other.com"
+)
+
+// newMemoryRepository returns a repository holding the mock user, with ID
+// mock.IDTest and mock.PasswordTest hashed by hasher, plus the other users
+// given by username.
+func newMemoryRepository(t *testing.T, hasher service.PasswordHasher, usernames ...string) *repository.Memory {
+	t.Helper()
+
+	repo := repository.NewMemory()
+
+	passwordHashed, err := hasher.Hash(mock.PasswordTest)
+	assert.Nil(t, err)
+
+	err = repo.InsertUser(context.TODO(), entity.User{
+		Username: mock.UsernameTest,
+		Password: passwordHashed,
+		Email:    mock.EmailTest,
+	})
+	assert.Nil(t, err)
+
+	for _, username := range usernames {
+		err = repo.InsertUser(context.TODO(), entity.User{
+			Username: username,
+			Password: passwordHashed,
+			Email:    username + "@other.com",
+		})
+		assert.Nil(t, err)
+	}
+
+	return repo
+}
+
 func TestGetAllUsers(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		name                         string
-		outID, outUsername, outEmail any
-		outErr                       string
+		name   string
+		outErr string
 	}{
 		{
-			name:        mock.NameNoError,
-			outID:       mock.IDTest,
-			outUsername: mock.UsernameTest,
-			outEmail:    mock.EmailTest,
-			outErr:      "",
-		},
-		{
-			name:        mock.NameErrorDBClosed,
-			outID:       mock.IDTest,
-			outUsername: mock.UsernameTest,
-			outEmail:    mock.EmailTest,
-			outErr:      "sql: database is closed",
-		},
-		{
-			name:        "ErrorScanRows",
-			outID:       "id",
-			outUsername: 1,
-			outEmail:    1,
-			outErr:      "Scan error on column index 0",
-		},
-		/* {
-			name:        nameErrorNoRows,
-			outID:       idTest,
-			outUsername: usernameTest,
-			outEmail:    emailTest,
-			outErr:      "asdfadfsafds",
-		}, */
+			name:   mock.NameNoError,
+			outErr: "",
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			outErr: mock.ErrDatabaseClosed,
+		},
 	} {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
@@ -53,38 +72,23 @@ func TestGetAllUsers(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
+			hasher := password.NewBcrypt(bcrypt.MinCost)
 
+			var repo service.UserRepository = newMemoryRepository(t, hasher)
 			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
+				repo = mock.FailingRepository{}
 			}
 
-			svc := service.GetService(db)
-
-			rows := sqlmock.NewRows(
-				[]string{
-					"id",
-					"username",
-					"email",
-				}).AddRow(
-				tt.outID,
-				tt.outUsername,
-				tt.outEmail,
-			)
+			svc := service.GetService(repo, hasher)
 
-			dbMock.ExpectQuery("SELECT id, username, email FROM users").WillReturnRows(rows)
-
-			_, err = svc.GetAllUsers()
+			users, _, err := svc.GetAllUsers(context.TODO(), entity.ListUsersRequest{})
 			if err != nil {
 				resultErr = err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
 				assert.Empty(t, resultErr)
+				assert.Equal(t, []entity.User{{ID: mock.IDTest, Username: mock.UsernameTest, Email: mock.EmailTest}}, users)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -92,38 +96,111 @@ func TestGetAllUsers(t *testing.T) {
 	}
 }
 
+func TestGetAllUsersPagination(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		outErr    string
+		inRequest entity.ListUsersRequest
+		outPages  [][]string
+	}{
+		{
+			name:      mock.NameNoError,
+			inRequest: entity.ListUsersRequest{Limit: 2},
+			outPages:  [][]string{{mock.UsernameTest, "carol"}, {"alice", "bob"}},
+		},
+		{
+			name: "NoErrorSortByUsernameDesc",
+			inRequest: entity.ListUsersRequest{
+				SortBy:     "username",
+				Descending: true,
+				Limit:      3,
+			},
+			outPages: [][]string{{mock.UsernameTest, "carol", "bob"}, {"alice"}},
+		},
+		{
+			name: "NoErrorFilters",
+			inRequest: entity.ListUsersRequest{
+				SortBy:         "email",
+				UsernamePrefix: "b",
+				EmailDomain:    "@OTHER.com",
+			},
+			outPages: [][]string{{"bob"}},
+		},
+		{
+			name:      "ErrorSortField",
+			inRequest: entity.ListUsersRequest{SortBy: "password"},
+			outErr:    service.ErrInvalidListOptions.Error(),
+		},
+		{
+			name:      "ErrorCursor",
+			inRequest: entity.ListUsersRequest{Cursor: "%%%"},
+			outErr:    service.ErrInvalidListOptions.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+
+			svc := service.GetService(newMemoryRepository(t, hasher, "carol", "alice", "bob"), hasher)
+
+			var pages [][]string
+
+			request := tt.inRequest
+
+			for {
+				users, nextCursor, err := svc.GetAllUsers(context.TODO(), request)
+				if err != nil {
+					assert.ErrorContains(t, err, tt.outErr)
+
+					return
+				}
+
+				page := make([]string, 0, len(users))
+				for _, user := range users {
+					page = append(page, user.Username)
+				}
+
+				pages = append(pages, page)
+
+				if nextCursor == "" {
+					break
+				}
+
+				request.Cursor = nextCursor
+			}
+
+			assert.Empty(t, tt.outErr)
+			assert.Equal(t, tt.outPages, pages)
+		})
+	}
+}
+
 func TestGetUserByID(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		name                               string
-		outUsername, outPassword, outEmail string
-		outErr                             string
-		inID                               int
+		name   string
+		outErr string
+		inID   int
 	}{
 		{
-			name:        mock.NameNoError,
-			inID:        mock.IDTest,
-			outUsername: mock.UsernameTest,
-			outPassword: mock.PasswordTest,
-			outEmail:    mock.EmailTest,
-			outErr:      "",
+			name:   mock.NameNoError,
+			inID:   mock.IDTest,
+			outErr: "",
 		},
 		{
-			name:        mock.NameErrorNoRows,
-			inID:        mock.IDTest,
-			outUsername: mock.UsernameTest,
-			outPassword: mock.PasswordTest,
-			outEmail:    mock.EmailTest,
-			outErr:      "",
+			name:   mock.NameErrorNoRows,
+			inID:   mock.IDTest + 1,
+			outErr: service.ErrUserNotFound.Error(),
 		},
 		{
-			name:        mock.NameErrorDBClosed,
-			inID:        mock.IDTest,
-			outUsername: mock.UsernameTest,
-			outPassword: mock.PasswordTest,
-			outEmail:    mock.EmailTest,
-			outErr:      "sql: database is closed",
+			name:   mock.NameErrorDBClosed,
+			inID:   mock.IDTest,
+			outErr: mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
@@ -132,46 +209,23 @@ func TestGetUserByID(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
+			hasher := password.NewBcrypt(bcrypt.MinCost)
 
+			var repo service.UserRepository = newMemoryRepository(t, hasher)
 			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
+				repo = mock.FailingRepository{}
 			}
 
-			svc := service.GetService(db)
-
-			rows := sqlmock.NewRows(
-				[]string{
-					"id",
-					"username",
-					"password",
-					"email",
-				}).AddRow(
-				tt.inID,
-				tt.outUsername,
-				tt.outPassword,
-				tt.outEmail,
-			)
-
-			if tt.name == mock.NameErrorNoRows {
-				rows = sqlmock.NewRows([]string{"id", "username", "password", "email"})
-			}
-
-			dbMock.ExpectQuery(
-				"^SELECT id, username, password, email FROM users",
-			).WithArgs(tt.inID).WillReturnRows(rows)
+			svc := service.GetService(repo, hasher)
 
-			_, err = svc.GetUserByID(tt.inID)
+			user, err := svc.GetUserByID(context.TODO(), tt.inID)
 			if err != nil {
 				resultErr = err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
 				assert.Empty(t, resultErr)
+				assert.Equal(t, mock.UsernameTest, user.Username)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -183,34 +237,33 @@ func TestGetUserByUsernameAndPassword(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		name                            string
-		inUsername, inPassword, inEmail string
-		outErr                          string
-		inID                            int
+		name                   string
+		inUsername, inPassword string
+		outErr                 string
 	}{
 		{
 			name:       mock.NameNoError,
-			inID:       mock.IDTest,
 			inUsername: mock.UsernameTest,
 			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
 			outErr:     "",
 		},
 		{
 			name:       mock.NameErrorNoRows,
-			inID:       mock.IDTest,
-			inUsername: mock.UsernameTest,
+			inUsername: otherUsernameTest,
 			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
-			outErr:     "",
+			outErr:     service.ErrInvalidCredentials.Error(),
+		},
+		{
+			name:       "ErrorWrongPassword",
+			inUsername: mock.UsernameTest,
+			inPassword: "wrong",
+			outErr:     service.ErrInvalidCredentials.Error(),
 		},
 		{
 			name:       mock.NameErrorDBClosed,
-			inID:       mock.IDTest,
 			inUsername: mock.UsernameTest,
 			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
-			outErr:     "sql: database is closed",
+			outErr:     mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
@@ -219,54 +272,170 @@ func TestGetUserByUsernameAndPassword(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
+			hasher := password.NewBcrypt(bcrypt.MinCost)
 
+			var repo service.UserRepository = newMemoryRepository(t, hasher)
 			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
+				repo = mock.FailingRepository{}
 			}
 
-			svc := service.GetService(db)
-
-			rows := sqlmock.NewRows(
-				[]string{
-					"id",
-					"username",
-					"password",
-					"email",
-				}).AddRow(
-				tt.inID,
-				tt.inUsername,
-				tt.inPassword,
-				tt.inEmail,
-			)
-
-			if tt.name == mock.NameErrorNoRows {
-				rows = sqlmock.NewRows([]string{"id", "username", "password", "email"})
+			svc := service.GetService(repo, hasher)
+
+			user, err := svc.GetUserByUsernameAndPassword(context.TODO(), tt.inUsername, tt.inPassword)
+			if err != nil {
+				resultErr = err.Error()
 			}
 
-			dbMock.ExpectQuery(
-				"^SELECT id, username, password, email FROM users",
-			).WithArgs(tt.inUsername, tt.inPassword).WillReturnRows(rows)
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+				assert.NotEmpty(t, user)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+				assert.Empty(t, user)
+			}
+		})
+	}
+}
+
+// countingHasher counts the passwords its PasswordHasher verifies.
+type countingHasher struct {
+	service.PasswordHasher
+	verified *atomic.Int32
+}
+
+func (h countingHasher) Verify(plainPassword, encodedHash string) (bool, error) {
+	h.verified.Add(1)
+
+	return h.PasswordHasher.Verify(plainPassword, encodedHash)
+}
+
+// TestGetUserByUsernameAndPasswordUnknownUsername checks that a password is
+// verified even for a username without user, so that the time it takes does
+// not tell whether the username exists.
+func TestGetUserByUsernameAndPasswordUnknownUsername(t *testing.T) {
+	t.Parallel()
+
+	bcryptHasher := password.NewBcrypt(bcrypt.MinCost)
+	hasher := countingHasher{PasswordHasher: bcryptHasher, verified: new(atomic.Int32)}
+	svc := service.GetService(newMemoryRepository(t, bcryptHasher), hasher)
+
+	_, err := svc.GetUserByUsernameAndPassword(context.TODO(), otherUsernameTest, mock.PasswordTest)
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+	assert.Equal(t, int32(1), hasher.verified.Load())
+}
+
+func TestGetUserByUsernameAndPasswordLegacy(t *testing.T) {
+	t.Parallel()
 
-			user, err := svc.GetUserByUsernameAndPassword(tt.inUsername, tt.inPassword)
+	for _, tt := range []struct {
+		name       string
+		inPassword string
+		outErr     string
+	}{
+		{
+			name:       mock.NameNoError,
+			inPassword: mock.PasswordTest,
+			outErr:     "",
+		},
+		{
+			name:       "ErrorWrongPassword",
+			inPassword: "wrong",
+			outErr:     service.ErrInvalidCredentials.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+
+			repo := repository.NewMemory()
+
+			err := repo.InsertUser(context.TODO(), entity.User{
+				Username: mock.UsernameTest,
+				Password: mock.LegacyHashTest,
+				Email:    mock.EmailTest,
+			})
+			assert.Nil(t, err)
+
+			svc := service.GetService(repo, hasher)
+
+			user, err := svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, tt.inPassword)
 			if err != nil {
 				resultErr = err.Error()
 			}
 
+			count, err := svc.CountLegacyPasswords(context.TODO())
+			assert.Nil(t, err)
+
 			if tt.name == mock.NameNoError {
 				assert.Empty(t, resultErr)
-				assert.NotEmpty(t, user)
+				assert.NotEqual(t, mock.LegacyHashTest, user.Password)
+
+				ok, verifyErr := hasher.Verify(mock.PasswordTest, user.Password)
+				assert.Nil(t, verifyErr)
+				assert.True(t, ok)
+				assert.Zero(t, count)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
+				assert.Empty(t, user)
+				assert.Equal(t, 1, count)
 			}
 		})
 	}
 }
 
+// TestGetUserByUsernameAndPasswordChangedHasher checks that the users keep
+// logging in after the hasher is changed, and that their password is rehashed
+// with the new one.
+func TestGetUserByUsernameAndPasswordChangedHasher(t *testing.T) {
+	t.Parallel()
+
+	argon2id := password.NewArgon2id(password.Argon2idParams{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	bcryptHasher := password.NewBcrypt(bcrypt.MinCost)
+
+	for _, tt := range []struct {
+		former  service.PasswordHasher
+		current password.Hasher
+		other   password.Hasher
+		name    string
+	}{
+		{name: "BcryptToArgon2id", former: bcryptHasher, current: argon2id, other: bcryptHasher},
+		{name: "Argon2idToBcrypt", former: argon2id, current: bcryptHasher, other: argon2id},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := newMemoryRepository(t, tt.former)
+			svc := service.GetService(repo, password.NewMulti(tt.current, tt.other))
+
+			_, err := svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, "wrong")
+			assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+
+			user, err := svc.GetUserByUsernameAndPassword(context.TODO(), mock.UsernameTest, mock.PasswordTest)
+			assert.Nil(t, err)
+			assert.False(t, tt.current.NeedsRehash(user.Password))
+
+			stored, err := repo.GetUserByID(context.TODO(), mock.IDTest)
+			assert.Nil(t, err)
+			assert.Equal(t, user.Password, stored.Password)
+
+			ok, err := tt.current.Verify(mock.PasswordTest, stored.Password)
+			assert.Nil(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
 func TestGetIDByUsername(t *testing.T) {
 	t.Parallel()
 
@@ -274,25 +443,21 @@ func TestGetIDByUsername(t *testing.T) {
 		name       string
 		inUsername string
 		outErr     string
-		inID       int
 	}{
 		{
 			name:       mock.NameNoError,
-			inID:       mock.IDTest,
 			inUsername: mock.UsernameTest,
 			outErr:     "",
 		},
 		{
 			name:       mock.NameErrorNoRows,
-			inID:       mock.IDTest,
-			inUsername: mock.UsernameTest,
-			outErr:     "",
+			inUsername: otherUsernameTest,
+			outErr:     service.ErrUserNotFound.Error(),
 		},
 		{
 			name:       mock.NameErrorDBClosed,
-			inID:       mock.IDTest,
 			inUsername: mock.UsernameTest,
-			outErr:     "sql: database is closed",
+			outErr:     mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
@@ -301,33 +466,23 @@ func TestGetIDByUsername(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
-			if err != nil {
-				assert.Error(t, err)
-			}
-			defer db.Close()
+			hasher := password.NewBcrypt(bcrypt.MinCost)
 
+			var repo service.UserRepository = newMemoryRepository(t, hasher)
 			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
+				repo = mock.FailingRepository{}
 			}
 
-			svc := service.GetService(db)
-
-			rows := sqlmock.NewRows([]string{"id"}).AddRow(tt.inID)
-
-			if tt.name == mock.NameErrorNoRows {
-				rows = sqlmock.NewRows([]string{"id"})
-			}
+			svc := service.GetService(repo, hasher)
 
-			dbMock.ExpectQuery("^SELECT id FROM users").WithArgs(tt.inUsername).WillReturnRows(rows)
-
-			_, err = svc.GetIDByUsername(tt.inUsername)
+			id, err := svc.GetIDByUsername(context.TODO(), tt.inUsername)
 			if err != nil {
 				resultErr = err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
 				assert.Empty(t, resultErr)
+				assert.Equal(t, mock.IDTest, id)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -339,30 +494,33 @@ func TestInsertUser(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		name                            string
-		inUsername, inPassword, inEmail string
-		outErr                          string
+		name                string
+		inUsername, inEmail string
+		outErr              string
 	}{
 		{
 			name:       mock.NameNoError,
-			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
+			inUsername: otherUsernameTest,
+			inEmail:    otherEmailTest,
 			outErr:     "",
 		},
 		{
-			name:       mock.NameErrorNoRows,
-			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
-			inEmail:    mock.EmailTest,
-			outErr:     "",
+			name:       mock.NameErrorDBClosed,
+			inUsername: otherUsernameTest,
+			inEmail:    otherEmailTest,
+			outErr:     mock.ErrDatabaseClosed,
 		},
 		{
-			name:       mock.NameErrorDBClosed,
+			name:       "ErrorUsernameTaken",
 			inUsername: mock.UsernameTest,
-			inPassword: mock.PasswordTest,
+			inEmail:    otherEmailTest,
+			outErr:     service.ErrUsernameTaken.Error(),
+		},
+		{
+			name:       "ErrorEmailTaken",
+			inUsername: otherUsernameTest,
 			inEmail:    mock.EmailTest,
-			outErr:     "sql: database is closed",
+			outErr:     service.ErrEmailTaken.Error(),
 		},
 	} {
 		tt := tt
@@ -371,38 +529,114 @@ func TestInsertUser(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+
+			var repo service.UserRepository = newMemoryRepository(t, hasher)
+			if tt.name == mock.NameErrorDBClosed {
+				repo = mock.FailingRepository{}
+			}
+
+			svc := service.GetService(repo, hasher)
+
+			err := svc.InsertUser(context.TODO(), tt.inUsername, mock.PasswordTest, tt.inEmail)
 			if err != nil {
-				assert.Error(t, err)
+				resultErr = err.Error()
 			}
-			defer db.Close()
 
+			if tt.name == mock.NameNoError {
+				assert.Empty(t, resultErr)
+
+				user, getErr := repo.GetUserByUsername(context.TODO(), tt.inUsername)
+				assert.Nil(t, getErr)
+				assert.NotEqual(t, mock.PasswordTest, user.Password)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	t.Parallel()
+
+	otherUsername, otherEmail, newPassword := otherUsernameTest, otherEmailTest, "new"
+
+	for _, tt := range []struct {
+		inPatch entity.UserPatch
+		name    string
+		outErr  string
+		inID    int
+	}{
+		{
+			name:    mock.NameNoError,
+			inID:    mock.IDTest,
+			inPatch: entity.UserPatch{Username: &otherUsername, Password: &newPassword},
+			outErr:  "",
+		},
+		{
+			name:    "NoErrorEmptyPatch",
+			inID:    mock.IDTest,
+			inPatch: entity.UserPatch{},
+			outErr:  "",
+		},
+		{
+			name:    mock.NameErrorNoRows,
+			inID:    mock.IDTest + 3,
+			inPatch: entity.UserPatch{Email: &otherEmail},
+			outErr:  service.ErrUserNotFound.Error(),
+		},
+		{
+			name:    "ErrorEmailTaken",
+			inID:    mock.IDTest + 1,
+			inPatch: entity.UserPatch{Email: &otherEmail},
+			outErr:  service.ErrEmailTaken.Error(),
+		},
+		{
+			name:    mock.NameErrorDBClosed,
+			inID:    mock.IDTest,
+			inPatch: entity.UserPatch{Email: &otherEmail},
+			outErr:  mock.ErrDatabaseClosed,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+
+			var repo service.UserRepository = newMemoryRepository(t, hasher, "bob")
 			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
+				repo = mock.FailingRepository{}
 			}
 
-			svc := service.GetService(db)
+			err := repo.InsertUser(context.TODO(), entity.User{Username: "carol", Email: otherEmailTest})
+			if tt.name != mock.NameErrorDBClosed {
+				assert.Nil(t, err)
+			}
 
-			dbMock.ExpectExec(
-				"^INSERT INTO users",
-			).WithArgs(
-				tt.inUsername,
-				tt.inPassword,
-				tt.inEmail,
-			).WillReturnResult(
-				sqlmock.NewResult(0, 1),
-			)
+			svc := service.GetService(repo, hasher)
 
-			err = svc.InsertUser(tt.inUsername, tt.inPassword, tt.inEmail)
+			user, err := svc.UpdateUser(context.TODO(), tt.inID, tt.inPatch)
 			if err != nil {
 				resultErr = err.Error()
 			}
 
-			if tt.name == mock.NameNoError {
+			if tt.outErr == "" {
 				assert.Empty(t, resultErr)
+				assert.Equal(t, tt.inID, user.ID)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
+
+			if tt.name == mock.NameNoError {
+				assert.Equal(t, otherUsernameTest, user.Username)
+
+				ok, verifyErr := hasher.Verify(newPassword, user.Password)
+				assert.Nil(t, verifyErr)
+				assert.True(t, ok)
+			}
 		})
 	}
 }
@@ -411,19 +645,27 @@ func TestDeleteUser(t *testing.T) {
 	t.Parallel()
 
 	for _, tt := range []struct {
-		name   string
-		outErr string
-		inID   int
+		name            string
+		outErr          string
+		inID            int
+		outRowsAffected int
 	}{
 		{
-			name:   mock.NameNoError,
-			inID:   mock.IDTest,
-			outErr: "",
+			name:            mock.NameNoError,
+			inID:            mock.IDTest,
+			outRowsAffected: 1,
+			outErr:          "",
+		},
+		{
+			name:            mock.NameErrorNoRows,
+			inID:            mock.IDTest + 1,
+			outRowsAffected: 0,
+			outErr:          "",
 		},
 		{
 			name:   mock.NameErrorDBClosed,
 			inID:   mock.IDTest,
-			outErr: "sql: database is closed",
+			outErr: mock.ErrDatabaseClosed,
 		},
 	} {
 		tt := tt
@@ -432,33 +674,78 @@ func TestDeleteUser(t *testing.T) {
 
 			var resultErr string
 
-			db, dbMock, err := sqlmock.New()
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+
+			var repo service.UserRepository = newMemoryRepository(t, hasher)
+			if tt.name == mock.NameErrorDBClosed {
+				repo = mock.FailingRepository{}
+			}
+
+			svc := service.GetService(repo, hasher)
+
+			rowsAffected, err := svc.DeleteUser(context.TODO(), tt.inID)
 			if err != nil {
-				assert.Error(t, err)
+				resultErr = err.Error()
 			}
-			defer db.Close()
 
-			if tt.name == mock.NameErrorDBClosed {
-				db.Close()
+			if tt.outErr == "" {
+				assert.Empty(t, resultErr)
+				assert.Equal(t, tt.outRowsAffected, rowsAffected)
+			} else {
+				assert.Contains(t, resultErr, tt.outErr)
 			}
+		})
+	}
+}
+
+func TestCountLegacyPasswords(t *testing.T) {
+	t.Parallel()
 
-			svc := service.GetService(db)
+	for _, tt := range []struct {
+		name     string
+		outErr   string
+		outCount int
+	}{
+		{
+			name:     mock.NameNoError,
+			outCount: 1,
+			outErr:   "",
+		},
+		{
+			name:   mock.NameErrorDBClosed,
+			outErr: mock.ErrDatabaseClosed,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var resultErr string
+
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+
+			var repo service.UserRepository = newMemoryRepository(t, hasher)
+			if tt.name == mock.NameErrorDBClosed {
+				repo = mock.FailingRepository{}
+			} else {
+				err := repo.InsertUser(context.TODO(), entity.User{
+					Username: otherUsernameTest,
+					Password: mock.LegacyHashTest,
+					Email:    otherEmailTest,
+				})
+				assert.Nil(t, err)
+			}
 
-			dbMock.ExpectExec(
-				"^DELETE FROM users",
-			).WithArgs(
-				tt.inID,
-			).WillReturnResult(
-				sqlmock.NewResult(0, 1),
-			)
+			svc := service.GetService(repo, hasher)
 
-			_, err = svc.DeleteUser(tt.inID)
+			count, err := svc.CountLegacyPasswords(context.TODO())
 			if err != nil {
 				resultErr = err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
 				assert.Empty(t, resultErr)
+				assert.Equal(t, tt.outCount, count)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+
+	"storage/internal/entity"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware decorates a Service.
+type Middleware func(Service) Service
+
+// tracingService runs every method of next in a span of its own.
+type tracingService struct {
+	next   Service
+	tracer trace.Tracer
+}
+
+const (
+	// instrumentationName names the tracer of the service.
+	instrumentationName = "storage/internal/service"
+
+	// userIDKey is the span attribute of the ID of the user a method acts on.
+	userIDKey = attribute.Key("user.id")
+)
+
+// TracingMiddleware returns a Middleware that runs every method of the
+// service in a span named after it, e.g. service.GetUserByID, with a tracer
+// of tp. Passwords are never recorded.
+func TracingMiddleware(tp trace.TracerProvider) Middleware {
+	return func(next Service) Service {
+		return tracingService{next: next, tracer: tp.Tracer(instrumentationName)}
+	}
+}
+
+// GetAllUsers ...
+func (s tracingService) GetAllUsers(
+	ctx context.Context,
+	opts entity.ListUsersRequest,
+) (users []entity.User, nextCursor string, err error) {
+	ctx, span := s.tracer.Start(ctx, "service.GetAllUsers")
+	defer func() { end(span, err) }()
+
+	return s.next.GetAllUsers(ctx, opts)
+}
+
+// GetUserByID ...
+func (s tracingService) GetUserByID(ctx context.Context, id int) (user entity.User, err error) {
+	ctx, span := s.tracer.Start(ctx, "service.GetUserByID", trace.WithAttributes(userIDKey.Int(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.GetUserByID(ctx, id)
+}
+
+// GetUserByUsernameAndPassword ...
+func (s tracingService) GetUserByUsernameAndPassword(
+	ctx context.Context,
+	username, plainPassword string,
+) (user entity.User, err error) {
+	ctx, span := s.tracer.Start(ctx, "service.GetUserByUsernameAndPassword")
+	defer func() { end(span, err) }()
+
+	return s.next.GetUserByUsernameAndPassword(ctx, username, plainPassword)
+}
+
+// GetIDByUsername ...
+func (s tracingService) GetIDByUsername(ctx context.Context, username string) (id int, err error) {
+	ctx, span := s.tracer.Start(ctx, "service.GetIDByUsername")
+	defer func() { end(span, err) }()
+
+	return s.next.GetIDByUsername(ctx, username)
+}
+
+// InsertUser ...
+func (s tracingService) InsertUser(ctx context.Context, username, plainPassword, email string) (err error) {
+	ctx, span := s.tracer.Start(ctx, "service.InsertUser")
+	defer func() { end(span, err) }()
+
+	return s.next.InsertUser(ctx, username, plainPassword, email)
+}
+
+// UpdateUser ...
+func (s tracingService) UpdateUser(ctx context.Context, id int, patch entity.UserPatch) (user entity.User, err error) {
+	ctx, span := s.tracer.Start(ctx, "service.UpdateUser", trace.WithAttributes(userIDKey.Int(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.UpdateUser(ctx, id, patch)
+}
+
+// DeleteUser ...
+func (s tracingService) DeleteUser(ctx context.Context, id int) (rowsAffected int, err error) {
+	ctx, span := s.tracer.Start(ctx, "service.DeleteUser", trace.WithAttributes(userIDKey.Int(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.DeleteUser(ctx, id)
+}
+
+// CountLegacyPasswords ...
+func (s tracingService) CountLegacyPasswords(ctx context.Context) (count int, err error) {
+	ctx, span := s.tracer.Start(ctx, "service.CountLegacyPasswords")
+	defer func() { end(span, err) }()
+
+	return s.next.CountLegacyPasswords(ctx)
+}
+
+// UnlockUser ...
+func (s tracingService) UnlockUser(ctx context.Context, id int) (err error) {
+	ctx, span := s.tracer.Start(ctx, "service.UnlockUser", trace.WithAttributes(userIDKey.Int(id)))
+	defer func() { end(span, err) }()
+
+	return s.next.UnlockUser(ctx, id)
+}
+
+// end ends span, marking it as failed when err is not nil.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,58 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+
+	"storage/internal/entity/mock"
+	"storage/internal/password"
+	"storage/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		inID      int
+		outStatus codes.Code
+	}{
+		{
+			name:      mock.NameNoError,
+			inID:      mock.IDTest,
+			outStatus: codes.Unset,
+		},
+		{
+			name:      mock.NameErrorNoRows,
+			inID:      mock.IDTest + 1,
+			outStatus: codes.Error,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+			svc := service.TracingMiddleware(tp)(service.GetService(newMemoryRepository(t, hasher), hasher))
+
+			_, _ = svc.GetUserByID(context.TODO(), tt.inID)
+
+			spans := recorder.Ended()
+			if assert.Len(t, spans, 1) {
+				assert.Equal(t, "service.GetUserByID", spans[0].Name())
+				assert.Equal(t, tt.outStatus, spans[0].Status().Code)
+				assert.Contains(t, spans[0].Attributes(), attribute.Int("user.id", tt.inID))
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Package tlsconfig serves TLS, and optionally mutual TLS, from certificate
+// files that are reloaded whenever they change, without a restart.
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Files holds the paths of the PEM files of a server.
+type Files struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, if not empty, holds the CA bundle the certificates
+	// clients must present are verified against.
+	ClientCAFile string
+}
+
+// Reloader holds the certificate of the server and the CAs of the clients
+// as last loaded from its Files.
+type Reloader struct {
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	// loaded holds the contents of the files behind cert and clientCAs, to
+	// tell whether they changed.
+	loaded [3][]byte
+	files  Files
+	mu     sync.RWMutex
+}
+
+// debounce is how long Watch waits for the events of a change to settle, so
+// that a certificate and key written one after the other are loaded at once.
+const debounce = 100 * time.Millisecond
+
+var (
+	ErrNoClientCAs              = errors.New("no CA certificate found")
+	ErrNoClientCertificate      = errors.New("no client certificate")
+	ErrInvalidClientCertificate = errors.New("invalid client certificate")
+)
+
+// New returns a Reloader of files, failing if they cannot be loaded.
+func New(files Files) (*Reloader, error) {
+	r := &Reloader{files: files}
+
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Config returns a TLS configuration serving the certificate loaded last
+// and, if the Files have a ClientCAFile, requiring a client certificate
+// signed by the CAs loaded last.
+func (r *Reloader) Config() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+
+	// Clients are verified by verifyConnection rather than against a fixed
+	// ClientCAs pool, so that a new CA bundle applies to the next handshake.
+	// Unlike VerifyPeerCertificate, VerifyConnection runs on resumed sessions
+	// too, whose client certificate was verified against the former CAs.
+	if r.files.ClientCAFile != "" {
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = r.verifyConnection
+	}
+
+	return cfg
+}
+
+// Reload loads the files again. On failure, the files loaded last are kept.
+func (r *Reloader) Reload() error {
+	_, err := r.reload()
+
+	return err
+}
+
+// Watch reloads the files whenever they change until ctx is done, calling
+// report after every reload with the error that made it keep the previous
+// files, if any. It watches the directories of the files, so that the files
+// replaced by a rename, as editors and Kubernetes secrets do, are noticed
+// too.
+func (r *Reloader) Watch(ctx context.Context, report func(error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error to watch TLS files: %w", err)
+	}
+	defer watcher.Close()
+
+	for _, dir := range r.dirs() {
+		if err = watcher.Add(dir); err != nil {
+			return fmt.Errorf("error to watch %s: %w", dir, err)
+		}
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			timer.Reset(debounce)
+		case watchErr, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			report(fmt.Errorf("error to watch TLS files: %w", watchErr))
+		case <-timer.C:
+			// Other files of the directories changing is no reason to
+			// report anything.
+			if changed, reloadErr := r.reload(); changed || reloadErr != nil {
+				report(reloadErr)
+			}
+		}
+	}
+}
+
+// reload loads the files if their contents changed since the last load,
+// reporting whether they did.
+func (r *Reloader) reload() (changed bool, err error) {
+	var contents [3][]byte
+
+	for i, file := range [3]string{r.files.CertFile, r.files.KeyFile, r.files.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+
+		contents[i], err = os.ReadFile(file)
+		if err != nil {
+			return false, fmt.Errorf("error to read TLS file: %w", err)
+		}
+	}
+
+	r.mu.RLock()
+	loaded := r.loaded
+	r.mu.RUnlock()
+
+	if bytes.Equal(contents[0], loaded[0]) && bytes.Equal(contents[1], loaded[1]) &&
+		bytes.Equal(contents[2], loaded[2]) {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(contents[0], contents[1])
+	if err != nil {
+		return false, fmt.Errorf("error to load certificate %s: %w", r.files.CertFile, err)
+	}
+
+	var clientCAs *x509.CertPool
+
+	if r.files.ClientCAFile != "" {
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(contents[2]) {
+			return false, fmt.Errorf("%w in %s", ErrNoClientCAs, r.files.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.loaded = contents
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+func (r *Reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// verifyConnection verifies the chain presented by the client of a new or
+// resumed session against the CAs loaded last, for client authentication.
+func (r *Reloader) verifyConnection(state tls.ConnectionState) error {
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		return ErrNoClientCertificate
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	r.mu.RLock()
+	roots := r.clientCAs
+	r.mu.RUnlock()
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidClientCertificate, err)
+	}
+
+	return nil
+}
+
+// dirs returns the directories holding the files, without duplicates.
+func (r *Reloader) dirs() []string {
+	var dirs []string
+
+	seen := make(map[string]bool)
+
+	for _, file := range []string{r.files.CertFile, r.files.KeyFile, r.files.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+
+		if dir := filepath.Dir(file); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
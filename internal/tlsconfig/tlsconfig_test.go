@@ -0,0 +1,334 @@
+package tlsconfig_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"storage/internal/entity/mock"
+	"storage/internal/tlsconfig"
+	"storage/internal/tlsconfig/tlstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFiles writes a server certificate issued by ca, and ca as the client CA
+// bundle, to a temporary directory.
+func newFiles(t *testing.T, ca *tlstest.CA) tlsconfig.Files {
+	t.Helper()
+
+	dir := t.TempDir()
+	server := ca.Server(t)
+
+	return tlsconfig.Files{
+		CertFile:     tlstest.WriteFile(t, dir, "tls.crt", server.CertPEM),
+		KeyFile:      tlstest.WriteFile(t, dir, "tls.key", server.KeyPEM),
+		ClientCAFile: tlstest.WriteFile(t, dir, "ca.crt", ca.PEM),
+	}
+}
+
+// clientConfig returns the TLS configuration of a client trusting roots and
+// presenting client, if not nil.
+func clientConfig(t *testing.T, roots *x509.CertPool, client *tlstest.Cert) *tls.Config {
+	t.Helper()
+
+	clientCfg := &tls.Config{RootCAs: roots, ServerName: "localhost", MinVersion: tls.VersionTLS12}
+	if client != nil {
+		clientCfg.Certificates = []tls.Certificate{client.TLSCertificate(t)}
+	}
+
+	return clientCfg
+}
+
+// handshake runs a TLS handshake between a server of cfg and a client of
+// clientCfg. It returns the certificate the server presented and the client
+// the server verified, if any, or the error the server failed with.
+func handshake(
+	t *testing.T,
+	cfg *tls.Config,
+	clientCfg *tls.Config,
+) (serverCert *x509.Certificate, state tls.ConnectionState, err error) {
+	t.Helper()
+
+	// A TCP connection rather than a net.Pipe, whose unbuffered writes
+	// deadlock when the server sends its session ticket while the client
+	// sends its Finished message.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	clientDone := make(chan *x509.Certificate, 1)
+
+	go func() {
+		clientConn, dialErr := net.Dial("tcp", listener.Addr().String())
+		if dialErr != nil {
+			clientDone <- nil
+
+			return
+		}
+		defer clientConn.Close()
+
+		conn := tls.Client(clientConn, clientCfg)
+		if conn.Handshake() != nil {
+			clientDone <- nil
+
+			return
+		}
+
+		// TLS 1.3 servers verify the client after the client is done, so
+		// the client waits for the outcome, reading the session ticket
+		// before it.
+		_, _ = conn.Read(make([]byte, 1))
+
+		clientDone <- conn.ConnectionState().PeerCertificates[0]
+	}()
+
+	serverConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer serverConn.Close()
+
+	conn := tls.Server(serverConn, cfg)
+
+	err = conn.Handshake()
+	if err == nil {
+		state = conn.ConnectionState()
+
+		// TLS 1.3 servers requiring a client certificate only flush the
+		// session ticket with the first write.
+		_, _ = conn.Write([]byte{0})
+	}
+
+	serverConn.Close()
+
+	return <-clientDone, state, err
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	ca := tlstest.NewCA(t)
+	other := ca.Server(t)
+
+	for _, tt := range []struct {
+		inEdit func(t *testing.T, files *tlsconfig.Files)
+		name   string
+		outErr string
+	}{
+		{
+			name:   mock.NameNoError,
+			inEdit: func(*testing.T, *tlsconfig.Files) {},
+		},
+		{
+			name:   "NoClientCAs",
+			inEdit: func(_ *testing.T, files *tlsconfig.Files) { files.ClientCAFile = "" },
+		},
+		{
+			name:   "MissingKey",
+			inEdit: func(_ *testing.T, files *tlsconfig.Files) { files.KeyFile += ".missing" },
+			outErr: "no such file or directory",
+		},
+		{
+			name: "MismatchedKey",
+			inEdit: func(t *testing.T, files *tlsconfig.Files) {
+				files.KeyFile = tlstest.WriteFile(t, t.TempDir(), "tls.key", other.KeyPEM)
+			},
+			outErr: "private key does not match public key",
+		},
+		{
+			name: "InvalidClientCAs",
+			inEdit: func(t *testing.T, files *tlsconfig.Files) {
+				files.ClientCAFile = tlstest.WriteFile(t, t.TempDir(), "ca.crt", []byte("not a certificate"))
+			},
+			outErr: tlsconfig.ErrNoClientCAs.Error(),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			files := newFiles(t, ca)
+			tt.inEdit(t, &files)
+
+			_, err := tlsconfig.New(files)
+			if tt.outErr == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	t.Parallel()
+
+	ca := tlstest.NewCA(t)
+	client := ca.Client(t, "billing")
+	otherClient := tlstest.NewCA(t).Client(t, "billing")
+
+	for _, tt := range []struct {
+		inClient      *tlstest.Cert
+		name          string
+		inNoClientCAs bool
+		outVerified   bool
+		outErr        bool
+	}{
+		{name: mock.NameNoError, inClient: &client, outVerified: true},
+		{name: "NoClientCertificate", outErr: true},
+		{name: "OtherCA", inClient: &otherClient, outErr: true},
+		{name: "WithoutClientCAs", inClient: &client, inNoClientCAs: true},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			files := newFiles(t, ca)
+			if tt.inNoClientCAs {
+				files.ClientCAFile = ""
+			}
+
+			reloader, err := tlsconfig.New(files)
+			assert.Nil(t, err)
+
+			_, state, err := handshake(t, reloader.Config(), clientConfig(t, ca.Pool(), tt.inClient))
+			if tt.outErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.Nil(t, err)
+
+			if tt.outVerified {
+				if assert.Len(t, state.PeerCertificates, 1) {
+					assert.Equal(t, "billing", state.PeerCertificates[0].Subject.CommonName)
+				}
+			} else {
+				assert.Empty(t, state.PeerCertificates)
+			}
+		})
+	}
+}
+
+// TestClientAuthResumedSession checks that a client resuming its session is
+// verified again, so that it is rejected once its CA is no longer trusted.
+func TestClientAuthResumedSession(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		inVersion uint16
+	}{
+		{name: "TLS12", inVersion: tls.VersionTLS12},
+		{name: "TLS13", inVersion: tls.VersionTLS13},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ca := tlstest.NewCA(t)
+			client := ca.Client(t, "billing")
+			files := newFiles(t, ca)
+
+			reloader, err := tlsconfig.New(files)
+			assert.Nil(t, err)
+
+			cfg := reloader.Config()
+
+			clientCfg := clientConfig(t, ca.Pool(), &client)
+			clientCfg.MaxVersion = tt.inVersion
+			clientCfg.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+
+			_, state, err := handshake(t, cfg, clientCfg)
+			assert.Nil(t, err)
+			assert.False(t, state.DidResume)
+
+			_, state, err = handshake(t, cfg, clientCfg)
+			assert.Nil(t, err)
+			assert.True(t, state.DidResume)
+
+			tlstest.WriteFile(t, "", files.ClientCAFile, tlstest.NewCA(t).PEM)
+			assert.Nil(t, reloader.Reload())
+
+			_, _, err = handshake(t, cfg, clientCfg)
+			assert.ErrorIs(t, err, tlsconfig.ErrInvalidClientCertificate)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	ca := tlstest.NewCA(t)
+	files := newFiles(t, ca)
+
+	reloader, err := tlsconfig.New(files)
+	assert.Nil(t, err)
+
+	cfg := reloader.Config()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reports := make(chan error, 10)
+	watchDone := make(chan error, 1)
+
+	go func() {
+		watchDone <- reloader.Watch(ctx, func(err error) { reports <- err })
+	}()
+
+	// Give the watch time to start before the files change.
+	time.Sleep(100 * time.Millisecond)
+
+	// A new CA issues both the certificate of the server and that of the
+	// client, which the old CA no longer verifies.
+	newCA := tlstest.NewCA(t)
+	server := newCA.Server(t)
+	client := newCA.Client(t, "billing")
+	oldClient := ca.Client(t, "billing")
+
+	tlstest.WriteFile(t, "", files.CertFile, server.CertPEM)
+	tlstest.WriteFile(t, "", files.KeyFile, server.KeyPEM)
+	tlstest.WriteFile(t, "", files.ClientCAFile, newCA.PEM)
+
+	select {
+	case err = <-reports:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload reported")
+	}
+
+	serverCert, _, err := handshake(t, cfg, clientConfig(t, newCA.Pool(), &client))
+	assert.Nil(t, err)
+
+	if assert.NotNil(t, serverCert) {
+		assert.True(t, bytes.Equal(server.TLSCertificate(t).Certificate[0], serverCert.Raw))
+	}
+
+	_, _, err = handshake(t, cfg, clientConfig(t, newCA.Pool(), &oldClient))
+	assert.Error(t, err)
+
+	// A broken certificate is reported and the previous one kept.
+	tlstest.WriteFile(t, "", files.CertFile, []byte("not a certificate"))
+
+	select {
+	case err = <-reports:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no failed reload reported")
+	}
+
+	_, _, err = handshake(t, cfg, clientConfig(t, newCA.Pool(), &client))
+	assert.Nil(t, err)
+
+	cancel()
+	assert.Nil(t, <-watchDone)
+}
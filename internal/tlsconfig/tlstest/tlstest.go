@@ -0,0 +1,160 @@
+// Package tlstest issues the CAs and certificates of the TLS tests.
+package tlstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// CA is a certificate authority issuing certificates for tests.
+type CA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	// PEM is the certificate of the CA, for a CA bundle.
+	PEM []byte
+}
+
+// Cert is a certificate and its private key, both PEM encoded.
+type Cert struct {
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// serial numbers every certificate.
+var serial int64
+
+// NewCA returns a new self-signed CA.
+func NewCA(t *testing.T) *CA {
+	t.Helper()
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          nextSerial(),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	return &CA{cert: cert, key: key, PEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// Server issues a server certificate for localhost and 127.0.0.1.
+func (ca *CA) Server(t *testing.T) Cert {
+	t.Helper()
+
+	return ca.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+}
+
+// Client issues a client certificate with the given common name and URI SANs.
+func (ca *CA) Client(t *testing.T, commonName string, uris ...string) Cert {
+	t.Helper()
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	for _, uri := range uris {
+		parsed, err := url.Parse(uri)
+		assert.Nil(t, err)
+
+		template.URIs = append(template.URIs, parsed)
+	}
+
+	return ca.issue(t, template)
+}
+
+// Pool returns a pool holding only the CA.
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	return pool
+}
+
+// TLSCertificate returns c for a tls.Config.
+func (c Cert) TLSCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	cert, err := tls.X509KeyPair(c.CertPEM, c.KeyPEM)
+	assert.Nil(t, err)
+
+	return cert
+}
+
+// WriteFile writes data to the file of dir with the given name, returning its
+// path.
+func WriteFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	err := os.WriteFile(path, data, 0o600)
+	assert.Nil(t, err)
+
+	return path
+}
+
+func (ca *CA) issue(t *testing.T, template *x509.Certificate) Cert {
+	t.Helper()
+
+	key := newKey(t)
+
+	template.SerialNumber = nextSerial()
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	return Cert{
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	return key
+}
+
+func nextSerial() *big.Int {
+	return big.NewInt(atomic.AddInt64(&serial, 1))
+}
@@ -0,0 +1,33 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"storage/internal/auth"
+)
+
+// apiKeyFromHeader puts the API key of the request, from its X-API-Key or
+// Authorization header, in the context for the endpoint AuthMiddleware.
+func apiKeyFromHeader(ctx context.Context, r *http.Request) context.Context {
+	return auth.NewContext(ctx, auth.Secret(r.Header.Get(auth.Header), r.Header.Get("Authorization")))
+}
+
+// challengeToHeader tells the clients rejected for their API key how to
+// authenticate in the WWW-Authenticate header.
+func challengeToHeader(err error, w http.ResponseWriter) {
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="storage"`)
+	}
+}
+
+// identityFromTLS puts the Identity of the client certificate of the
+// request, if any, in the context.
+func identityFromTLS(ctx context.Context, r *http.Request) context.Context {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ctx
+	}
+
+	return auth.NewIdentityContext(ctx, auth.IdentityFromCertificate(r.TLS.PeerCertificates[0]))
+}
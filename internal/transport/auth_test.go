@@ -0,0 +1,75 @@
+package transport_test
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"storage/internal/auth"
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/tlsconfig"
+	"storage/internal/tlsconfig/tlstest"
+	"storage/internal/transport"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityFromTLS(t *testing.T) {
+	t.Parallel()
+
+	ca := tlstest.NewCA(t)
+	dir := t.TempDir()
+	server := ca.Server(t)
+
+	reloader, err := tlsconfig.New(tlsconfig.Files{
+		CertFile:     tlstest.WriteFile(t, dir, "tls.crt", server.CertPEM),
+		KeyFile:      tlstest.WriteFile(t, dir, "tls.key", server.KeyPEM),
+		ClientCAFile: tlstest.WriteFile(t, dir, "ca.crt", ca.PEM),
+	})
+	assert.Nil(t, err)
+
+	// GetUserByID answers with the identity of the caller as username.
+	router := mux.NewRouter()
+	transport.RegisterRoutes(router, endpoint.Endpoints{
+		GetUserByID: func(ctx context.Context, _ any) (any, error) {
+			identity, _ := auth.IdentityFromContext(ctx)
+
+			return entity.UserErrorResponse{User: entity.User{Username: identity.Name}}, nil
+		},
+	})
+
+	httpServer := httptest.NewUnstartedServer(router)
+	httpServer.Listener = tls.NewListener(httpServer.Listener, reloader.Config())
+	httpServer.Start()
+	t.Cleanup(httpServer.Close)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      ca.Pool(),
+		Certificates: []tls.Certificate{ca.Client(t, "billing", "spiffe://example.org/billing").TLSCertificate(t)},
+		MinVersion:   tls.VersionTLS12,
+	}}}
+
+	req, err := http.NewRequestWithContext(
+		context.TODO(), http.MethodGet, "https://"+httpServer.Listener.Addr().String()+"/users/1", nil,
+	)
+	assert.Nil(t, err)
+
+	resp, err := client.Do(req)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		User entity.User `json:"user"`
+	}
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "spiffe://example.org/billing", body.User.Username)
+}
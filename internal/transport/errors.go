@@ -0,0 +1,89 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/requestid"
+	"storage/internal/service"
+
+	kitratelimit "github.com/go-kit/kit/ratelimit"
+)
+
+// Machine-readable codes carried in entity.ErrorBody.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeInvalidListOptions = "invalid_list_options"
+	CodeUserNotFound       = "user_not_found"
+	CodeUsernameTaken      = "username_taken"
+	CodeEmailTaken         = "email_taken"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeUserLocked         = "user_locked"
+	CodeUnauthenticated    = "unauthenticated"
+	CodeForbidden          = "forbidden"
+	CodeRateLimited        = "rate_limited"
+	CodeTimeout            = "timeout"
+	CodeInternal           = "internal_error"
+)
+
+var ErrBadRequest = errors.New("bad request")
+
+// EncodeError is the httptransport.ErrorEncoder of every handler. It writes an
+// entity.ErrorBody with the status code and machine-readable code of err and
+// the request ID of ctx, which it also echoes in the X-Request-ID header.
+// Errors without a code of their own are only logged, and reach the client as
+// the generic text of their status, so that no internal detail leaks.
+// Rate limited requests are told when to retry in the Retry-After header, and
+// those without a valid API key how to authenticate in WWW-Authenticate.
+func EncodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	logError(ctx, err)
+	traceError(ctx, err)
+
+	status, code := errorStatus(err)
+
+	requestIDToHeader(ctx, w)
+	retryAfterToHeader(err, w)
+	challengeToHeader(err, w)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	message := err.Error()
+	if code == CodeInternal {
+		message = http.StatusText(status)
+	}
+
+	_ = json.NewEncoder(w).Encode(entity.ErrorBody{Err: message, Code: code, RequestID: requestid.FromContext(ctx)})
+}
+
+func errorStatus(err error) (status int, code string) {
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		return http.StatusBadRequest, CodeBadRequest
+	case errors.Is(err, service.ErrInvalidListOptions):
+		return http.StatusBadRequest, CodeInvalidListOptions
+	case errors.Is(err, service.ErrUserNotFound):
+		return http.StatusNotFound, CodeUserNotFound
+	case errors.Is(err, service.ErrUsernameTaken):
+		return http.StatusConflict, CodeUsernameTaken
+	case errors.Is(err, service.ErrEmailTaken):
+		return http.StatusConflict, CodeEmailTaken
+	case errors.Is(err, service.ErrInvalidCredentials):
+		return http.StatusUnauthorized, CodeInvalidCredentials
+	case errors.Is(err, service.ErrUserLocked):
+		return http.StatusLocked, CodeUserLocked
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized, CodeUnauthenticated
+	case errors.Is(err, auth.ErrForbidden):
+		return http.StatusForbidden, CodeForbidden
+	case errors.Is(err, kitratelimit.ErrLimited):
+		return http.StatusTooManyRequests, CodeRateLimited
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, CodeTimeout
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
@@ -0,0 +1,130 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"storage/internal/auth"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/ratelimit"
+	"storage/internal/service"
+	"storage/internal/transport"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeError(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		in              error
+		name            string
+		outCode         string
+		outErr          string
+		outRetryAfter   string
+		outAuthenticate string
+		outStatus       int
+	}{
+		{
+			name:      "BadRequest",
+			in:        fmt.Errorf("%w: failed to decode request: EOF", transport.ErrBadRequest),
+			outStatus: http.StatusBadRequest,
+			outCode:   transport.CodeBadRequest,
+		},
+		{
+			name:      "UserNotFound",
+			in:        service.ErrUserNotFound,
+			outStatus: http.StatusNotFound,
+			outCode:   transport.CodeUserNotFound,
+		},
+		{
+			name:      "UsernameTaken",
+			in:        service.ErrUsernameTaken,
+			outStatus: http.StatusConflict,
+			outCode:   transport.CodeUsernameTaken,
+		},
+		{
+			name:      "EmailTaken",
+			in:        service.ErrEmailTaken,
+			outStatus: http.StatusConflict,
+			outCode:   transport.CodeEmailTaken,
+		},
+		{
+			name:      "InvalidCredentials",
+			in:        service.ErrInvalidCredentials,
+			outStatus: http.StatusUnauthorized,
+			outCode:   transport.CodeInvalidCredentials,
+		},
+		{
+			name:      "UserLocked",
+			in:        service.ErrUserLocked,
+			outStatus: http.StatusLocked,
+			outCode:   transport.CodeUserLocked,
+		},
+		{
+			name:            "Unauthenticated",
+			in:              auth.ErrUnauthenticated,
+			outStatus:       http.StatusUnauthorized,
+			outCode:         transport.CodeUnauthenticated,
+			outAuthenticate: `Bearer realm="storage"`,
+		},
+		{
+			name:      "Forbidden",
+			in:        fmt.Errorf("%w: %q", auth.ErrForbidden, auth.ScopeWrite),
+			outStatus: http.StatusForbidden,
+			outCode:   transport.CodeForbidden,
+		},
+		{
+			name:          "RateLimited",
+			in:            &ratelimit.Error{RetryAfter: 1500 * time.Millisecond},
+			outStatus:     http.StatusTooManyRequests,
+			outCode:       transport.CodeRateLimited,
+			outRetryAfter: "2",
+		},
+		{
+			name:      "Timeout",
+			in:        fmt.Errorf("error to get all users: %w", context.DeadlineExceeded),
+			outStatus: http.StatusGatewayTimeout,
+			outCode:   transport.CodeTimeout,
+		},
+		{
+			name:      mock.NameErrorDBClosed,
+			in:        fmt.Errorf("error to get user by ID: %w", errors.New(mock.ErrDatabaseClosed)),
+			outStatus: http.StatusInternalServerError,
+			outCode:   transport.CodeInternal,
+			outErr:    http.StatusText(http.StatusInternalServerError),
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body entity.ErrorBody
+
+			w := httptest.NewRecorder()
+
+			transport.EncodeError(context.TODO(), tt.in, w)
+
+			err := json.NewDecoder(w.Body).Decode(&body)
+			assert.Nil(t, err)
+
+			assert.Equal(t, tt.outStatus, w.Code)
+			assert.Equal(t, tt.outCode, body.Code)
+			if tt.outErr == "" {
+				assert.Equal(t, tt.in.Error(), body.Err)
+			} else {
+				assert.Equal(t, tt.outErr, body.Err)
+				assert.NotContains(t, body.Err, mock.ErrDatabaseClosed)
+			}
+			assert.Equal(t, tt.outRetryAfter, w.Header().Get("Retry-After"))
+			assert.Equal(t, tt.outAuthenticate, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
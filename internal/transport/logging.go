@@ -0,0 +1,90 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"storage/internal/auth"
+	"storage/internal/requestid"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+)
+
+// requestLog collects what the transport logs about a request while it is
+// being served.
+type requestLog struct {
+	begin time.Time
+	err   error
+	route string
+}
+
+type requestLogKey struct{}
+
+// LoggingOptions returns the server options that log every request with its
+// method, route, status code, latency, request ID, client certificate
+// identity and error, if any. The request and response bodies are left to the
+// endpoint LoggingMiddleware, which redacts them.
+func LoggingOptions(logger log.Logger) []httptransport.ServerOption {
+	return []httptransport.ServerOption{
+		httptransport.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+			entry := &requestLog{begin: time.Now()}
+
+			if route := mux.CurrentRoute(r); route != nil {
+				entry.route, _ = route.GetPathTemplate()
+			}
+
+			return context.WithValue(ctx, requestLogKey{}, entry)
+		}),
+		httptransport.ServerFinalizer(func(ctx context.Context, code int, r *http.Request) {
+			entry, ok := ctx.Value(requestLogKey{}).(*requestLog)
+			if !ok {
+				return
+			}
+
+			keyvals := []any{
+				"method", r.Method,
+				"route", entry.route,
+				"status", code,
+				"took", time.Since(entry.begin),
+				"request_id", requestid.FromContext(ctx),
+			}
+
+			if identity, ok := auth.IdentityFromContext(ctx); ok {
+				keyvals = append(keyvals, "client", identity.Name)
+			}
+
+			_ = logger.Log(append(keyvals, "err", entry.err)...)
+		}),
+	}
+}
+
+// requestIDFromHeader puts the X-Request-ID header of r into ctx, or a new
+// request ID when the header is missing or not a valid request ID.
+func requestIDFromHeader(ctx context.Context, r *http.Request) context.Context {
+	id := r.Header.Get(requestid.Header)
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+
+	return requestid.NewContext(ctx, id)
+}
+
+// requestIDToHeader echoes the request ID of ctx in the X-Request-ID header
+// of the response.
+func requestIDToHeader(ctx context.Context, w http.ResponseWriter) context.Context {
+	if id := requestid.FromContext(ctx); id != "" {
+		w.Header().Set(requestid.Header, id)
+	}
+
+	return ctx
+}
+
+// logError records err to be logged along with the request of ctx.
+func logError(ctx context.Context, err error) {
+	if entry, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		entry.err = err
+	}
+}
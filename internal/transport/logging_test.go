@@ -0,0 +1,172 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/repository"
+	"storage/internal/requestid"
+	"storage/internal/service"
+	"storage/internal/transport"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggingOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		inURL     string
+		inID      string
+		outFields []string
+	}{
+		{
+			name:  "GetUserByID",
+			inURL: "/users/1",
+			inID:  "abc-123",
+			outFields: []string{
+				"method=GET", "route=/users/{id:[0-9]+}", "status=200", "request_id=abc-123", "err=null",
+			},
+		},
+		{
+			name:  "GetUserByIDNotFound",
+			inURL: "/users/9",
+			outFields: []string{
+				"status=404", `err="user not found"`,
+			},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := repository.NewMemory()
+
+			err := repo.InsertUser(context.TODO(), entity.User{
+				Username: mock.UsernameTest,
+				Password: mock.PasswordTest,
+				Email:    mock.EmailTest,
+			})
+			assert.Nil(t, err)
+
+			var buf bytes.Buffer
+
+			router := mux.NewRouter()
+			transport.RegisterRoutes(
+				router,
+				endpoint.MakeEndpoints(service.GetService(repo, nil)),
+				transport.LoggingOptions(log.NewLogfmtLogger(&buf))...,
+			)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.inURL, nil)
+
+			if tt.inID != "" {
+				r.Header.Set(requestid.Header, tt.inID)
+			}
+
+			router.ServeHTTP(w, r)
+
+			for _, field := range tt.outFields {
+				assert.Contains(t, buf.String(), field)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		inURL     string
+		inID      string
+		outStatus int
+		outSameID bool
+	}{
+		{
+			name:      mock.NameNoError,
+			inURL:     "/users/1",
+			inID:      "abc-123",
+			outStatus: http.StatusOK,
+			outSameID: true,
+		},
+		{
+			name:      "Generated",
+			inURL:     "/users/1",
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "Invalid",
+			inURL:     "/users/1",
+			inID:      "abc\x1b[31m",
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "ErrorBody",
+			inURL:     "/users/9",
+			inID:      "abc-123",
+			outStatus: http.StatusNotFound,
+			outSameID: true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := repository.NewMemory()
+
+			err := repo.InsertUser(context.TODO(), entity.User{
+				Username: mock.UsernameTest,
+				Password: mock.PasswordTest,
+				Email:    mock.EmailTest,
+			})
+			assert.Nil(t, err)
+
+			var buf bytes.Buffer
+
+			router := mux.NewRouter()
+			transport.RegisterRoutes(
+				router,
+				endpoint.MakeEndpoints(service.GetService(repo, nil)),
+				transport.LoggingOptions(log.NewLogfmtLogger(&buf))...,
+			)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.inURL, nil)
+			r.Header.Set(requestid.Header, tt.inID)
+
+			router.ServeHTTP(w, r)
+
+			id := w.Header().Get(requestid.Header)
+
+			assert.Equal(t, tt.outStatus, w.Code)
+			assert.True(t, requestid.Valid(id))
+			assert.Contains(t, buf.String(), "request_id="+id)
+
+			if tt.outSameID {
+				assert.Equal(t, tt.inID, id)
+			} else {
+				assert.NotEqual(t, tt.inID, id)
+			}
+
+			if tt.outStatus != http.StatusOK {
+				var body entity.ErrorBody
+
+				err = json.NewDecoder(w.Body).Decode(&body)
+				assert.Nil(t, err)
+				assert.Equal(t, id, body.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"storage/internal/ratelimit"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// RateLimitOptions returns the server options that key the client of every
+// request by its IP address, for the endpoint RateLimitMiddleware, which
+// keys authenticated clients by their API key instead. The X-Forwarded-For
+// header tells the address of the client only for requests from
+// trustedProxies.
+func RateLimitOptions(trustedProxies []netip.Prefix) []httptransport.ServerOption {
+	return []httptransport.ServerOption{
+		httptransport.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+			addr := ratelimit.ClientAddr(r.RemoteAddr, r.Header.Values("X-Forwarded-For"), trustedProxies)
+
+			return ratelimit.NewContext(ctx, ratelimit.ClientKey(addr))
+		}),
+	}
+}
+
+// retryAfterToHeader tells in the Retry-After header, in whole seconds
+// rounded up, how long the client rejected with err has to wait.
+func retryAfterToHeader(err error, w http.ResponseWriter) {
+	var limitErr *ratelimit.Error
+
+	if !errors.As(err, &limitErr) || limitErr.RetryAfter <= 0 {
+		return
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limitErr.RetryAfter.Seconds()))))
+}
@@ -0,0 +1,135 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"storage/internal/auth"
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/ratelimit"
+	"storage/internal/repository"
+	"storage/internal/service"
+	"storage/internal/transport"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name string
+		// inKeys are the X-API-Key headers of two requests, the second from
+		// inRemoteAddr.
+		inKeys [2]string
+		// inForwardedFor are the X-Forwarded-For headers of the two
+		// requests, which are trusted with inTrusted.
+		inForwardedFor [2]string
+		inRemoteAddr   string
+		inTrusted      bool
+		inAuth         bool
+		outStatus      int
+	}{
+		{name: "SameIP", outStatus: http.StatusTooManyRequests},
+		{name: "RotatingKeys", inKeys: [2]string{"sk_a", "sk_b"}, outStatus: http.StatusTooManyRequests},
+		{
+			name:      "RotatingKeysWithAuth",
+			inKeys:    [2]string{"sk_a", "sk_b"},
+			inAuth:    true,
+			outStatus: http.StatusTooManyRequests,
+		},
+		{name: "OtherIP", inRemoteAddr: "192.0.2.2:1234", outStatus: http.StatusOK},
+		{
+			name:           "ForwardedUntrusted",
+			inForwardedFor: [2]string{"198.51.100.1", "198.51.100.2"},
+			outStatus:      http.StatusTooManyRequests,
+		},
+		{
+			name:           "ForwardedTrusted",
+			inForwardedFor: [2]string{"198.51.100.1", "198.51.100.2"},
+			inTrusted:      true,
+			outStatus:      http.StatusOK,
+		},
+		{
+			name:           "ForwardedTrustedSameClient",
+			inForwardedFor: [2]string{"198.51.100.1", "203.0.113.1, 198.51.100.1"},
+			inTrusted:      true,
+			outStatus:      http.StatusTooManyRequests,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := repository.NewMemory()
+
+			err := repo.InsertUser(context.TODO(), entity.User{
+				Username: mock.UsernameTest,
+				Password: mock.PasswordTest,
+				Email:    mock.EmailTest,
+			})
+			assert.Nil(t, err)
+
+			endpoints := endpoint.MakeEndpoints(service.GetService(repo, nil))
+			if tt.inAuth {
+				endpoints = endpoint.AuthorizeEndpoints(endpoints)
+			}
+
+			endpoints, err = endpoint.RateLimitEndpoints(
+				endpoints,
+				map[string]*ratelimit.Limiter{
+					"GetUserByID": ratelimit.New(ratelimit.Limit{Rate: rate.Every(time.Minute), Burst: 1}),
+				},
+			)
+			assert.Nil(t, err)
+
+			if tt.inAuth {
+				endpoints = endpoint.AuthenticateEndpoints(endpoints, auth.NewAuthenticator(repo))
+			}
+
+			// httptest requests come from 192.0.2.1.
+			var trusted []netip.Prefix
+			if tt.inTrusted {
+				trusted = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+			}
+
+			router := mux.NewRouter()
+			transport.RegisterRoutes(router, endpoints, transport.RateLimitOptions(trusted)...)
+
+			var w *httptest.ResponseRecorder
+
+			for i, key := range tt.inKeys {
+				w = httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+
+				if i == 1 && tt.inRemoteAddr != "" {
+					r.RemoteAddr = tt.inRemoteAddr
+				}
+
+				if key != "" {
+					r.Header.Set("X-API-Key", key)
+				}
+
+				if tt.inForwardedFor[i] != "" {
+					r.Header.Set("X-Forwarded-For", tt.inForwardedFor[i])
+				}
+
+				router.ServeHTTP(w, r)
+			}
+
+			assert.Equal(t, tt.outStatus, w.Code)
+
+			if tt.outStatus == http.StatusTooManyRequests {
+				assert.Equal(t, "60", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
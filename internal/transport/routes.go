@@ -0,0 +1,61 @@
+package transport
+
+import (
+	"net/http"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+
+	kitendpoint "github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes mounts every endpoint on router, both the REST routes and
+// the legacy ones that read their parameters from a JSON body. The API key
+// of every request is put in the context for the endpoint AuthMiddleware,
+// along with the Identity of its client certificate, if any.
+func RegisterRoutes(router *mux.Router, endpoints endpoint.Endpoints, options ...httptransport.ServerOption) {
+	options = append([]httptransport.ServerOption{
+		httptransport.ServerErrorEncoder(EncodeError),
+		httptransport.ServerBefore(requestIDFromHeader, apiKeyFromHeader, identityFromTLS),
+		httptransport.ServerAfter(requestIDToHeader),
+	}, options...)
+
+	handler := func(e kitendpoint.Endpoint, dec httptransport.DecodeRequestFunc) http.Handler {
+		return httptransport.NewServer(e, dec, EncodeResponse, options...)
+	}
+
+	getAllUsersHandler := handler(endpoints.GetAllUsers, DecodeListUsersRequest())
+	countLegacyPasswordsHandler := handler(endpoints.CountLegacyPasswords, DecodeRequestWithoutBody())
+
+	// REST routes.
+	router.Methods(http.MethodGet).Path("/users/{id:[0-9]+}").
+		Handler(handler(endpoints.GetUserByID, DecodeIDFromPath()))
+	router.Methods(http.MethodGet).Path("/users").Queries("username", "{username}").
+		Handler(handler(endpoints.GetIDByUsername, DecodeUsernameFromQuery()))
+	router.Methods(http.MethodGet).Path("/users").Handler(getAllUsersHandler)
+	router.Methods(http.MethodPost).Path("/users").
+		Handler(handler(endpoints.InsertUser, DecodeRequest(entity.UsernamePasswordEmailRequest{})))
+	router.Methods(http.MethodPatch).Path("/users/{id:[0-9]+}").
+		Handler(handler(endpoints.UpdateUser, DecodeUpdateUserRequest()))
+	router.Methods(http.MethodDelete).Path("/users/{id:[0-9]+}").
+		Handler(handler(endpoints.DeleteUser, DecodeIDFromPath()))
+	router.Methods(http.MethodPost).Path("/auth/verify").
+		Handler(handler(endpoints.GetUserByUsernameAndPassword, DecodeRequest(entity.UsernamePasswordRequest{})))
+	router.Methods(http.MethodGet).Path("/stats/legacy_passwords").Handler(countLegacyPasswordsHandler)
+	router.Methods(http.MethodPost).Path("/users/{id:[0-9]+}/unlock").
+		Handler(handler(endpoints.UnlockUser, DecodeIDFromPath()))
+
+	// Legacy routes, kept for backward compatibility.
+	router.Methods(http.MethodGet).Path("/user/id").
+		Handler(handler(endpoints.GetUserByID, DecodeRequest(entity.IDRequest{})))
+	router.Methods(http.MethodGet).Path("/user/username_password").
+		Handler(handler(endpoints.GetUserByUsernameAndPassword, DecodeRequest(entity.UsernamePasswordRequest{})))
+	router.Methods(http.MethodGet).Path("/id/username").
+		Handler(handler(endpoints.GetIDByUsername, DecodeRequest(entity.UsernameRequest{})))
+	router.Methods(http.MethodPost).Path("/user").
+		Handler(handler(endpoints.InsertUser, DecodeRequest(entity.UsernamePasswordEmailRequest{})))
+	router.Methods(http.MethodDelete).Path("/user").
+		Handler(handler(endpoints.DeleteUser, DecodeRequest(entity.IDRequest{})))
+}
@@ -0,0 +1,168 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/password"
+	"storage/internal/repository"
+	"storage/internal/service"
+	"storage/internal/transport"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestRegisterRoutes(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name      string
+		inMethod  string
+		inURL     string
+		inBody    string
+		outStatus int
+	}{
+		{
+			name:      "GetUserByID",
+			inMethod:  http.MethodGet,
+			inURL:     "/users/1",
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "GetUserByIDNotFound",
+			inMethod:  http.MethodGet,
+			inURL:     "/users/9",
+			outStatus: http.StatusNotFound,
+		},
+		{
+			name:      "GetIDByUsername",
+			inMethod:  http.MethodGet,
+			inURL:     "/users?username=" + mock.UsernameTest,
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "GetAllUsers",
+			inMethod:  http.MethodGet,
+			inURL:     "/users",
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "GetAllUsersInvalidSort",
+			inMethod:  http.MethodGet,
+			inURL:     "/users?sort=password",
+			outStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "InsertUser",
+			inMethod:  http.MethodPost,
+			inURL:     "/users",
+			inBody:    `{"username": "new", "password": "password", "email": "new@new.com"}`,
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "InsertUserUsernameTaken",
+			inMethod:  http.MethodPost,
+			inURL:     "/users",
+			inBody:    usernamePasswordEmailRequestJSON,
+			outStatus: http.StatusConflict,
+		},
+		{
+			name:      "InsertUserBadRequest",
+			inMethod:  http.MethodPost,
+			inURL:     "/users",
+			inBody:    "{",
+			outStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "UpdateUser",
+			inMethod:  http.MethodPatch,
+			inURL:     "/users/1",
+			inBody:    `{"email": "new@new.com"}`,
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "UpdateUserEmailTaken",
+			inMethod:  http.MethodPatch,
+			inURL:     "/users/1",
+			inBody:    `{"email": "other@other.com"}`,
+			outStatus: http.StatusConflict,
+		},
+		{
+			name:      "DeleteUser",
+			inMethod:  http.MethodDelete,
+			inURL:     "/users/1",
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "VerifyCredentials",
+			inMethod:  http.MethodPost,
+			inURL:     "/auth/verify",
+			inBody:    usernamePasswordRequestJSON,
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "VerifyCredentialsInvalid",
+			inMethod:  http.MethodPost,
+			inURL:     "/auth/verify",
+			inBody:    `{"username": "username", "password": "wrong"}`,
+			outStatus: http.StatusUnauthorized,
+		},
+		{
+			name:      "UnlockUser",
+			inMethod:  http.MethodPost,
+			inURL:     "/users/1/unlock",
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "UnlockUserNotFound",
+			inMethod:  http.MethodPost,
+			inURL:     "/users/9/unlock",
+			outStatus: http.StatusNotFound,
+		},
+		{
+			name:      "LegacyGetUserByID",
+			inMethod:  http.MethodGet,
+			inURL:     "/user/id",
+			inBody:    idRequestJSON,
+			outStatus: http.StatusOK,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			hasher := password.NewBcrypt(bcrypt.MinCost)
+
+			passwordHashed, err := hasher.Hash(mock.PasswordTest)
+			assert.Nil(t, err)
+
+			repo := repository.NewMemory()
+
+			for _, user := range []entity.User{
+				{Username: mock.UsernameTest, Password: passwordHashed, Email: mock.EmailTest},
+				{Username: "other", Password: passwordHashed, Email: "other@other.com"},
+			} {
+				err = repo.InsertUser(context.TODO(), user)
+				assert.Nil(t, err)
+			}
+
+			router := mux.NewRouter()
+			transport.RegisterRoutes(router, endpoint.MakeEndpoints(service.GetService(repo, hasher)))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tt.inMethod, tt.inURL, strings.NewReader(tt.inBody))
+
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.outStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type serverSpanKey struct{}
+
+// instrumentationName names the tracer of the transport.
+const instrumentationName = "storage/internal/transport"
+
+// TracingOptions returns the server options that serve every request in a
+// span named after its method and route, e.g. "GET /users/{id:[0-9]+}", with
+// a tracer of tp. The span continues the trace of the W3C traceparent header
+// of the request, if any, and fails on 5xx status codes.
+func TracingOptions(tp trace.TracerProvider) []httptransport.ServerOption {
+	tracer := tp.Tracer(instrumentationName)
+	propagator := propagation.TraceContext{}
+
+	return []httptransport.ServerOption{
+		httptransport.ServerBefore(func(ctx context.Context, r *http.Request) context.Context {
+			ctx = propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+
+			name := r.Method
+			attributes := []attribute.KeyValue{
+				semconv.HTTPMethodKey.String(r.Method),
+				semconv.HTTPTargetKey.String(r.URL.Path),
+			}
+
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					name += " " + template
+					attributes = append(attributes, semconv.HTTPRouteKey.String(template))
+				}
+			}
+
+			ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+
+			return context.WithValue(ctx, serverSpanKey{}, span)
+		}),
+		httptransport.ServerFinalizer(func(ctx context.Context, code int, _ *http.Request) {
+			span, ok := ctx.Value(serverSpanKey{}).(trace.Span)
+			if !ok {
+				return
+			}
+
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(code))
+
+			if code >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(code))
+			}
+
+			span.End()
+		}),
+	}
+}
+
+// traceError records err in the span of the request of ctx, if any.
+func traceError(ctx context.Context, err error) {
+	if span, ok := ctx.Value(serverSpanKey{}).(trace.Span); ok {
+		span.RecordError(err)
+	}
+}
@@ -0,0 +1,90 @@
+package transport_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"storage/internal/endpoint"
+	"storage/internal/entity"
+	"storage/internal/entity/mock"
+	"storage/internal/repository"
+	"storage/internal/service"
+	"storage/internal/transport"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingOptions(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name          string
+		inURL         string
+		inTraceparent string
+		outTraceID    string
+		outStatus     int
+	}{
+		{
+			name:          mock.NameNoError,
+			inURL:         "/users/1",
+			inTraceparent: "00-4bf92f3577b34da6a3ce929b0e0e4736-00f067aa0ba902b7-01",
+			outTraceID:    "4bf92f3577b34da6a3ce929b0e0e4736",
+			outStatus:     http.StatusOK,
+		},
+		{
+			name:      "GetUserByIDNotFound",
+			inURL:     "/users/9",
+			outStatus: http.StatusNotFound,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			repo := repository.NewMemory()
+
+			err := repo.InsertUser(context.TODO(), entity.User{
+				Username: mock.UsernameTest,
+				Password: mock.PasswordTest,
+				Email:    mock.EmailTest,
+			})
+			assert.Nil(t, err)
+
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			router := mux.NewRouter()
+			transport.RegisterRoutes(
+				router,
+				endpoint.MakeEndpoints(service.GetService(repo, nil)),
+				transport.TracingOptions(tp)...,
+			)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tt.inURL, nil)
+
+			if tt.inTraceparent != "" {
+				r.Header.Set("traceparent", tt.inTraceparent)
+			}
+
+			router.ServeHTTP(w, r)
+
+			spans := recorder.Ended()
+			if assert.Len(t, spans, 1) {
+				assert.Equal(t, "GET /users/{id:[0-9]+}", spans[0].Name())
+				assert.Contains(t, spans[0].Attributes(), attribute.Int("http.status_code", tt.outStatus))
+
+				if tt.outTraceID != "" {
+					assert.Equal(t, tt.outTraceID, spans[0].SpanContext().TraceID().String())
+					assert.True(t, spans[0].Parent().IsRemote())
+				}
+			}
+		})
+	}
+}
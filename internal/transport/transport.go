@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"storage/internal/entity"
 
+	"github.com/go-kit/kit/endpoint"
 	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
 )
 
 // DecodeRequestWithoutBody ...
@@ -28,7 +31,7 @@ func DecodeRequest[req entity.IDRequest |
 ) httptransport.DecodeRequestFunc {
 	return func(_ context.Context, r *http.Request) (any, error) {
 		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-			return nil, fmt.Errorf("failed to decode request: %w", err)
+			return nil, fmt.Errorf("%w: failed to decode request: %v", ErrBadRequest, err)
 		}
 
 		return request, nil
@@ -36,10 +39,137 @@ func DecodeRequest[req entity.IDRequest |
 }
 
 // EncodeResponse ...
-func EncodeResponse(_ context.Context, w http.ResponseWriter, response any) error {
+func EncodeResponse(ctx context.Context, w http.ResponseWriter, response any) error {
+	if f, ok := response.(endpoint.Failer); ok && f.Failed() != nil {
+		EncodeError(ctx, f.Failed(), w)
+
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		return fmt.Errorf("failed to encode response: %w", err)
 	}
 
 	return nil
 }
+
+// DecodeIDFromPath decodes an entity.IDRequest from the {id} route variable.
+func DecodeIDFromPath() httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (any, error) {
+		id, err := idFromPath(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return entity.IDRequest{ID: id}, nil
+	}
+}
+
+// DecodeUsernameFromQuery decodes an entity.UsernameRequest from the
+// "username" query parameter.
+func DecodeUsernameFromQuery() httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (any, error) {
+		username := r.URL.Query().Get("username")
+		if username == "" {
+			return nil, fmt.Errorf("%w: missing username", ErrBadRequest)
+		}
+
+		return entity.UsernameRequest{Username: username}, nil
+	}
+}
+
+// DecodeUpdateUserRequest decodes an entity.UpdateUserRequest from the {id}
+// route variable and a JSON Merge Patch (RFC 7396) body. Members left out of
+// the patch are not changed; since every field of a user is required, a null
+// member, which would remove it, is rejected.
+func DecodeUpdateUserRequest() httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (any, error) {
+		id, err := idFromPath(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var members map[string]json.RawMessage
+
+		if err = json.NewDecoder(r.Body).Decode(&members); err != nil {
+			return nil, fmt.Errorf("%w: failed to decode merge patch: %v", ErrBadRequest, err)
+		}
+
+		var patch entity.UserPatch
+
+		for name, value := range members {
+			var field **string
+
+			switch name {
+			case "username":
+				field = &patch.Username
+			case "email":
+				field = &patch.Email
+			case "password":
+				field = &patch.Password
+			default:
+				return nil, fmt.Errorf("%w: unknown member %q", ErrBadRequest, name)
+			}
+
+			if string(value) == "null" {
+				return nil, fmt.Errorf("%w: member %q cannot be removed", ErrBadRequest, name)
+			}
+
+			var s string
+
+			if err = json.Unmarshal(value, &s); err != nil || s == "" {
+				return nil, fmt.Errorf("%w: member %q must be a non-empty string", ErrBadRequest, name)
+			}
+
+			*field = &s
+		}
+
+		return entity.UpdateUserRequest{ID: id, Patch: patch}, nil
+	}
+}
+
+func idFromPath(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid id: %v", ErrBadRequest, err)
+	}
+
+	return id, nil
+}
+
+// DecodeListUsersRequest decodes an entity.ListUsersRequest from the limit,
+// cursor, sort, order, usernamePrefix and emailDomain query parameters, all
+// of them optional.
+func DecodeListUsersRequest() httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (any, error) {
+		query := r.URL.Query()
+
+		request := entity.ListUsersRequest{
+			Cursor:         query.Get("cursor"),
+			SortBy:         query.Get("sort"),
+			UsernamePrefix: query.Get("usernamePrefix"),
+			EmailDomain:    query.Get("emailDomain"),
+		}
+
+		if limit := query.Get("limit"); limit != "" {
+			var err error
+
+			request.Limit, err = strconv.Atoi(limit)
+			if err != nil || request.Limit < 1 {
+				return nil, fmt.Errorf("%w: limit must be a positive integer", ErrBadRequest)
+			}
+		}
+
+		switch query.Get("order") {
+		case "", "asc":
+		case "desc":
+			request.Descending = true
+		default:
+			return nil, fmt.Errorf("%w: order must be asc or desc", ErrBadRequest)
+		}
+
+		return request, nil
+	}
+}
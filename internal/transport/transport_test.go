@@ -3,6 +3,7 @@ package transport_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,8 +11,10 @@ import (
 
 	"storage/internal/entity"
 	"storage/internal/entity/mock"
+	"storage/internal/service"
 	"storage/internal/transport"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -216,13 +219,16 @@ func TestEncodeResponse(t *testing.T) {
 			t.Parallel()
 			var resultErr string
 
-			err := transport.EncodeResponse(context.TODO(), httptest.NewRecorder(), tt.in)
+			w := httptest.NewRecorder()
+
+			err := transport.EncodeResponse(context.TODO(), w, tt.in)
 			if err != nil {
 				resultErr = err.Error()
 			}
 
 			if tt.name == mock.NameNoError {
 				assert.Empty(t, resultErr)
+				assert.Equal(t, http.StatusOK, w.Code)
 			} else {
 				assert.Contains(t, resultErr, tt.outErr)
 			}
@@ -230,6 +236,44 @@ func TestEncodeResponse(t *testing.T) {
 	}
 }
 
+func TestEncodeResponseFailed(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		in        any
+		name      string
+		outStatus int
+	}{
+		{
+			name:      mock.NameNoError,
+			in:        entity.UserErrorResponse{User: entity.User{ID: mock.IDTest}},
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      mock.NameErrorNoRows,
+			in:        entity.UserErrorResponse{Err: service.ErrUserNotFound},
+			outStatus: http.StatusNotFound,
+		},
+		{
+			name:      mock.NameErrorDBClosed,
+			in:        entity.UsersErrorResponse{Err: errors.New(mock.ErrDatabaseClosed)},
+			outStatus: http.StatusInternalServerError,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+
+			err := transport.EncodeResponse(context.TODO(), w, tt.in)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.outStatus, w.Code)
+		})
+	}
+}
+
 func getRequests() (myReqs *myRequests, err error) {
 	idReq, err := http.NewRequest(
 		http.MethodPost,
@@ -280,3 +324,209 @@ func getRequests() (myReqs *myRequests, err error) {
 		badReq:                   badReq,
 	}, nil
 }
+
+func TestDecodeIDFromPath(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		inID   string
+		outErr string
+		outID  int
+	}{
+		{
+			name:  mock.NameNoError,
+			inID:  "1",
+			outID: mock.IDTest,
+		},
+		{
+			name:   "BadRequest",
+			inID:   "one",
+			outErr: "invalid id",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, "/users/"+tt.inID, nil)
+			r = mux.SetURLVars(r, map[string]string{"id": tt.inID})
+
+			req, err := transport.DecodeIDFromPath()(context.TODO(), r)
+			if tt.name == mock.NameNoError {
+				assert.Nil(t, err)
+				assert.Equal(t, entity.IDRequest{ID: tt.outID}, req)
+			} else {
+				assert.ErrorIs(t, err, transport.ErrBadRequest)
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestDecodeUsernameFromQuery(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name        string
+		inURL       string
+		outUsername string
+		outErr      string
+	}{
+		{
+			name:        mock.NameNoError,
+			inURL:       "/users?username=" + mock.UsernameTest,
+			outUsername: mock.UsernameTest,
+		},
+		{
+			name:   "BadRequest",
+			inURL:  "/users",
+			outErr: "missing username",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, tt.inURL, nil)
+
+			req, err := transport.DecodeUsernameFromQuery()(context.TODO(), r)
+			if tt.name == mock.NameNoError {
+				assert.Nil(t, err)
+				assert.Equal(t, entity.UsernameRequest{Username: tt.outUsername}, req)
+			} else {
+				assert.ErrorIs(t, err, transport.ErrBadRequest)
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestDecodeUpdateUserRequest(t *testing.T) {
+	t.Parallel()
+
+	username, email := mock.UsernameTest, mock.EmailTest
+
+	for _, tt := range []struct {
+		name     string
+		inID     string
+		inBody   string
+		outErr   string
+		outPatch entity.UserPatch
+	}{
+		{
+			name:     mock.NameNoError,
+			inID:     "1",
+			inBody:   `{"username": "username", "email": "email@email.com"}`,
+			outPatch: entity.UserPatch{Username: &username, Email: &email},
+		},
+		{
+			name:     "NoErrorEmptyPatch",
+			inID:     "1",
+			inBody:   `{}`,
+			outPatch: entity.UserPatch{},
+		},
+		{
+			name:   "ErrorBadID",
+			inID:   "one",
+			inBody: `{}`,
+			outErr: "invalid id",
+		},
+		{
+			name:   "ErrorNotObject",
+			inID:   "1",
+			inBody: `["username"]`,
+			outErr: "failed to decode merge patch",
+		},
+		{
+			name:   "ErrorNullMember",
+			inID:   "1",
+			inBody: `{"email": null}`,
+			outErr: `member "email" cannot be removed`,
+		},
+		{
+			name:   "ErrorUnknownMember",
+			inID:   "1",
+			inBody: `{"id": 2}`,
+			outErr: `unknown member "id"`,
+		},
+		{
+			name:   "ErrorEmptyMember",
+			inID:   "1",
+			inBody: `{"password": ""}`,
+			outErr: `member "password" must be a non-empty string`,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPatch, "/users/"+tt.inID, bytes.NewBufferString(tt.inBody))
+			r = mux.SetURLVars(r, map[string]string{"id": tt.inID})
+
+			req, err := transport.DecodeUpdateUserRequest()(context.TODO(), r)
+			if tt.outErr == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, entity.UpdateUserRequest{ID: mock.IDTest, Patch: tt.outPatch}, req)
+			} else {
+				assert.ErrorIs(t, err, transport.ErrBadRequest)
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
+
+func TestDecodeListUsersRequest(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		name   string
+		inURL  string
+		outErr string
+		out    entity.ListUsersRequest
+	}{
+		{
+			name:  mock.NameNoError,
+			inURL: "/users",
+			out:   entity.ListUsersRequest{},
+		},
+		{
+			name:  "NoErrorAllParameters",
+			inURL: "/users?limit=10&cursor=abc&sort=email&order=desc&usernamePrefix=user&emailDomain=email.com",
+			out: entity.ListUsersRequest{
+				Limit:          10,
+				Cursor:         "abc",
+				SortBy:         "email",
+				Descending:     true,
+				UsernamePrefix: "user",
+				EmailDomain:    "email.com",
+			},
+		},
+		{
+			name:   "ErrorLimit",
+			inURL:  "/users?limit=-1",
+			outErr: "limit must be a positive integer",
+		},
+		{
+			name:   "ErrorOrder",
+			inURL:  "/users?order=up",
+			outErr: "order must be asc or desc",
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodGet, tt.inURL, nil)
+
+			req, err := transport.DecodeListUsersRequest()(context.TODO(), r)
+			if tt.outErr == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.out, req)
+			} else {
+				assert.ErrorIs(t, err, transport.ErrBadRequest)
+				assert.ErrorContains(t, err, tt.outErr)
+			}
+		})
+	}
+}
@@ -1,17 +1,64 @@
+# Every call but health and metrics needs an API key with the scope of its endpoint
+# API_KEY=$(docker-compose exec -T storage /app/main apikey create test admin)
+
 # GetAllUsers
-sudo curl -XGET localhost:7070/users
+sudo curl -H"X-API-Key: $API_KEY" -XGET localhost:7070/users
 
 # GetUserByID
-# curl -XGET -d'{"id":1}' localhost:7070/user/id
+# curl -H"X-API-Key: $API_KEY" -XGET -d'{"id":1}' localhost:7070/user/id
 
 # GetUserByUsernameAndPassword
-# curl -XGET -d'{"username":"cesar","password":"01234"}' localhost:7070/user/username_password
+# curl -H"X-API-Key: $API_KEY" -XGET -d'{"username":"cesar","password":"01234"}' localhost:7070/user/username_password
 
 # GetIDByUsername
-# curl -XGET -d'{"username":"cesar"}' localhost:7070/id/username
+# curl -H"X-API-Key: $API_KEY" -XGET -d'{"username":"cesar"}' localhost:7070/id/username
 
 # Insert User
-# curl -XPOST -d'{"username":"arturo","password":"nava","email":"arthurnavah@gmail.com"}' localhost:7070/user
+# curl -H"X-API-Key: $API_KEY" -XPOST -d'{"username":"arturo","password":"nava","email":"arthurnavah@gmail.com"}' localhost:7070/user
 
 # DeleteUserByUsername
-# curl -XDELETE -d'{"username":"arturo","password":"nava","email":"arthurnavah@gmail.com"}' localhost:7070/user
+# curl -H"X-API-Key: $API_KEY" -XDELETE -d'{"username":"arturo","password":"nava","email":"arthurnavah@gmail.com"}' localhost:7070/user
+
+# CountLegacyPasswords
+# curl -H"X-API-Key: $API_KEY" -XGET localhost:7070/stats/legacy_passwords
+
+# REST routes
+
+# GetUserByID
+# curl -H"X-API-Key: $API_KEY" -XGET localhost:7070/users/1
+
+# GetIDByUsername
+# curl -H"X-API-Key: $API_KEY" -XGET 'localhost:7070/users?username=cesar'
+
+# Insert User
+# curl -H"X-API-Key: $API_KEY" -XPOST -d'{"username":"arturo","password":"nava","email":"arthurnavah@gmail.com"}' localhost:7070/users
+
+# DeleteUser
+# curl -H"X-API-Key: $API_KEY" -XDELETE localhost:7070/users/3
+
+# Verify credentials: 423 Locked after lockout_threshold failures in a row
+# curl -H"X-API-Key: $API_KEY" -XPOST -d'{"username":"cesar","password":"01234"}' localhost:7070/auth/verify
+
+# UnlockUser
+# curl -H"X-API-Key: $API_KEY" -XPOST localhost:7070/users/1/unlock
+
+# UpdateUser
+# curl -H"X-API-Key: $API_KEY" -XPATCH -H'Content-Type: application/merge-patch+json' -d'{"email":"cesar@example.com"}' localhost:7070/users/1
+
+# GetAllUsers, paginated: follow nextCursor from the previous page
+# curl -H"X-API-Key: $API_KEY" -XGET 'localhost:7070/users?limit=10&sort=username&order=desc&usernamePrefix=c&emailDomain=gmail.com'
+# curl -H"X-API-Key: $API_KEY" -XGET 'localhost:7070/users?limit=10&sort=username&order=desc&cursor=<nextCursor>'
+
+# Health
+# curl -XGET localhost:7070/healthz
+# curl -XGET localhost:7070/readyz
+# curl -XGET localhost:7070/status
+
+# Metrics
+# curl -XGET localhost:9090/metrics
+
+# Rate limiting: 429 with Retry-After once over the rate_limits of the endpoint
+# for i in $(seq 50); do curl -H"X-API-Key: $API_KEY" -s -o /dev/null -w '%{http_code} ' localhost:7070/users; done
+
+# Request ID: echoed in X-Request-ID and in error bodies, generated when missing
+# curl -H"X-API-Key: $API_KEY" -i -H'X-Request-ID: my-request-1' localhost:7070/users/1